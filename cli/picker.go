@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// pickerHeight is the number of matching items shown at once.
+const pickerHeight = 10
+
+var ErrPickCancelled = errors.New("pick cancelled")
+
+// PickItem is an item that can be picked with Pick. Kind is shown next to the
+// label e.g. branch or tag, and Preview below the list when the item is
+// selected. Value is what the item stands for when it differs from the label.
+type PickItem struct {
+	Label   string
+	Kind    string
+	Preview string
+	Value   string
+}
+
+// IsTerminal reports whether the file is an interactive terminal.
+func IsTerminal(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd()))
+}
+
+// Pick lets the user fuzzy find and pick an item from the items. The items
+// are filtered as the user types, the arrow keys or ctrl-p and ctrl-n move the
+// selection, enter picks the selected item and escape or ctrl-c cancels.
+func Pick(in *os.File, out io.Writer, prompt string, items []PickItem) (*PickItem, error) {
+	if len(items) == 0 {
+		return nil, errors.New("nothing to pick from")
+	}
+
+	restore, err := rawMode(in)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	picker := &picker{out: out, prompt: prompt, items: items}
+	picker.filter()
+	picker.render()
+	defer picker.clear()
+
+	reader := bufio.NewReader(in)
+
+	for {
+		key, _, err := reader.ReadRune()
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case '\r', '\n':
+			if len(picker.matches) == 0 {
+				continue
+			}
+			item := picker.matches[picker.selected]
+			return &item, nil
+		case 3: // ctrl-c
+			return nil, ErrPickCancelled
+		case 27: // escape or an escape sequence e.g. an arrow key
+			if reader.Buffered() == 0 {
+				return nil, ErrPickCancelled
+			}
+
+			sequence := make([]byte, 2)
+			if _, err := io.ReadFull(reader, sequence); err != nil {
+				return nil, err
+			}
+
+			switch string(sequence) {
+			case "[A":
+				picker.move(-1)
+			case "[B":
+				picker.move(1)
+			}
+		case 16: // ctrl-p
+			picker.move(-1)
+		case 14: // ctrl-n
+			picker.move(1)
+		case 127, 8: // backspace
+			if len(picker.query) > 0 {
+				picker.query = picker.query[:len(picker.query)-1]
+				picker.filter()
+			}
+		default:
+			if unicode.IsPrint(key) {
+				picker.query = append(picker.query, key)
+				picker.filter()
+			}
+		}
+
+		picker.render()
+	}
+}
+
+type picker struct {
+	out      io.Writer
+	prompt   string
+	items    []PickItem
+	query    []rune
+	matches  []PickItem
+	selected int
+	lines    int
+}
+
+func (p *picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+
+	p.selected = (p.selected + delta + len(p.matches)) % len(p.matches)
+}
+
+// filter keeps the items that fuzzy match the query, best matches first.
+func (p *picker) filter() {
+	type scored struct {
+		item  PickItem
+		score int
+	}
+
+	var matches []scored
+
+	for _, item := range p.items {
+		if score, ok := FuzzyMatch(string(p.query), item.Label); ok {
+			matches = append(matches, scored{item: item, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	p.matches = p.matches[:0]
+	for _, match := range matches {
+		p.matches = append(p.matches, match.item)
+	}
+
+	p.selected = 0
+}
+
+// render redraws the picker in place of the previous render.
+func (p *picker) render() {
+	p.clear()
+
+	var lines []string
+
+	lines = append(lines, fmt.Sprintf("%s> %s", p.prompt, string(p.query)))
+
+	start := 0
+	if p.selected >= pickerHeight {
+		start = p.selected - pickerHeight + 1
+	}
+
+	for i := start; i < len(p.matches) && i < start+pickerHeight; i++ {
+		marker := " "
+		if i == p.selected {
+			marker = ">"
+		}
+		lines = append(lines, fmt.Sprintf("%s %s  (%s)", marker, p.matches[i].Label, p.matches[i].Kind))
+	}
+
+	lines = append(lines, fmt.Sprintf("  %d/%d", len(p.matches), len(p.items)))
+
+	if len(p.matches) > 0 && p.matches[p.selected].Preview != "" {
+		lines = append(lines, "")
+		for _, line := range strings.Split(strings.TrimRight(p.matches[p.selected].Preview, "\n"), "\n") {
+			lines = append(lines, "  "+line)
+		}
+	}
+
+	// The terminal is in raw mode, so lines have to return the carriage.
+	fmt.Fprint(p.out, strings.Join(lines, "\r\n"))
+	p.lines = len(lines)
+}
+
+// clear removes the previous render from the terminal.
+func (p *picker) clear() {
+	if p.lines == 0 {
+		return
+	}
+
+	if p.lines > 1 {
+		fmt.Fprintf(p.out, "\x1b[%dA", p.lines-1)
+	}
+	fmt.Fprint(p.out, "\r\x1b[J")
+	p.lines = 0
+}
+
+// FuzzyMatch reports whether all the characters of the query appear in order
+// in the text, ignoring case. Matches at the start of the text, after a
+// separator and consecutive matches score higher.
+func FuzzyMatch(query string, text string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+
+	queryRunes := []rune(strings.ToLower(query))
+	textRunes := []rune(strings.ToLower(text))
+
+	score := 0
+	previous := -2
+	q := 0
+
+	for i, r := range textRunes {
+		if q == len(queryRunes) {
+			break
+		}
+
+		if r != queryRunes[q] {
+			continue
+		}
+
+		switch {
+		case i == 0:
+			score += 3
+		case strings.ContainsRune("/-_. ", textRunes[i-1]):
+			score += 2
+		}
+
+		if previous == i-1 {
+			score += 2
+		}
+
+		score++
+		previous = i
+		q++
+	}
+
+	if q < len(queryRunes) {
+		return 0, false
+	}
+
+	// Prefer shorter texts when the matches are otherwise equal.
+	return score*100 - len(textRunes), true
+}
+
+// rawMode puts the terminal into raw mode and returns a function that
+// restores the previous mode.
+func rawMode(in *os.File) (func(), error) {
+	fd := int(in.Fd())
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("interactive picker requires a terminal: %w", err)
+	}
+
+	return func() {
+		_ = term.Restore(fd, state)
+	}, nil
+}
@@ -22,6 +22,9 @@ var commitCmd = &cobra.Command{
   
   To only stage file changes apply a flag --stage. The files won't be recorded
   until the next call for commit.
+
+  Committing is refused on the branches matching rules.protected_branch_patterns
+  in .gong/config. When no patterns are configured no branch is protected.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := commit(args)
@@ -10,6 +10,7 @@ import (
 	"path"
 	"testing"
 
+	"github.com/erikjuhani/git-gong/config"
 	"github.com/erikjuhani/git-gong/gong"
 	lib "github.com/libgit2/git2go/v31"
 )
@@ -151,3 +152,74 @@ func TestCommitCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestCommitProtectedBranchCmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		patterns  []string
+		committed bool
+	}{
+		{
+			name:      "Command gong commit with no protected branch patterns. Should commit, no branch is protected",
+			committed: true,
+		},
+		{
+			name:     "Command gong commit on a protected branch. Should refuse to commit",
+			patterns: []string{"main"},
+		},
+		{
+			name:      "Command gong commit on a branch the patterns do not match. Should commit",
+			patterns:  []string{"release/*"},
+			committed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, clean, err := gong.TestRepo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clean()
+
+			if _, err := repo.Seed("default-commit"); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, pattern := range tt.patterns {
+				config.ProtectedBranchPatterns.AddPattern(pattern)
+			}
+			defer func() { *config.ProtectedBranchPatterns = config.Patterns{} }()
+
+			before, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ioutil.WriteFile(path.Join(repo.Path, "a.file"), []byte("a\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Chdir(repo.Path); err != nil {
+				t.Fatal(err)
+			}
+
+			rootCmd.SetArgs([]string{commitCmd.Name(), "-m", "protected"})
+			rootCmd.SetErr(bytes.NewBuffer(nil))
+			defer rootCmd.SetErr(nil)
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			head, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if committed := head.ID.String() != before.ID.String(); committed != tt.committed {
+				t.Fatalf("expected committed %t, got %t", tt.committed, committed)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(conflictsCmd)
+}
+
+var conflictsCmd = &cobra.Command{
+	Use:   "conflicts",
+	Short: "List the files with unresolved conflicts.",
+	Long: `List the files with unresolved conflicts left by a merge or a switch that
+  stopped because of conflicts.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		conflicts, err := repo.Conflicts()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		if len(conflicts) == 0 {
+			cmd.Println("no conflicts")
+			return
+		}
+
+		for _, path := range conflicts {
+			cmd.Printf("C %s\n", path)
+		}
+	},
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 
@@ -18,8 +19,19 @@ func init() {
 		createReleaseCmd,
 		createTagCmd,
 	)
+
+	createFlags()
 }
 
+var (
+	branchType     string
+	branchTicket   string
+	branchFrom     string
+	branchTrack    bool
+	switchToBranch bool
+	releaseChannel string
+)
+
 // TODO: write long descriptions
 var createCmd = &cobra.Command{
 	Use:   "create [subcommand]",
@@ -31,8 +43,21 @@ var createCmd = &cobra.Command{
 var createBranchCmd = &cobra.Command{
 	Use:   "branch [branchname]",
 	Short: "Creates a new branch.",
-	Long:  ``,
-	Args:  cobra.ExactArgs(1),
+	Long: `Creates a new branch with branchname.
+
+  With the --type flag the branch name is generated from the branch templates
+  defined in .gong/config and the argument is used as a short description, e.g.
+  gong create branch --type feature --ticket ABC-123 "short description"
+  creates a branch feature/ABC-123-short-description with the template
+  feature/{ticket}-{description}. Without configured templates the template
+  {type}/{ticket}-{description} is used, otherwise only the configured types
+  are accepted.
+
+  By default the branch starts from the current HEAD. Use --from to start the
+  branch from a branch, tag, release, remote branch or a commit hash. With
+  --track the remote branch given with --from is set as the upstream.
+  Use --switch to switch to the created branch.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		repo, err := gong.Open()
 		if err != nil {
@@ -41,7 +66,29 @@ var createBranchCmd = &cobra.Command{
 		}
 		defer gong.Free(repo)
 
-		branch, err := repo.CreateLocalBranch(args[0])
+		branchName := args[0]
+
+		if branchType != "" {
+			branchName, err = gong.BranchNameFromTemplate(branchType, branchTicket, args[0])
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+		}
+
+		if branchTrack && branchFrom == "" {
+			cmd.PrintErr(errors.New("--track requires a remote branch given with --from"))
+			return
+		}
+
+		var branch *gong.Branch
+
+		if branchFrom != "" {
+			branch, err = repo.CreateBranchFrom(branchName, branchFrom, branchTrack)
+		} else {
+			branch, err = repo.CreateLocalBranch(branchName)
+		}
+
 		if err != nil {
 			cmd.PrintErr(err)
 			return
@@ -49,6 +96,17 @@ var createBranchCmd = &cobra.Command{
 		defer gong.Free(branch)
 
 		cmd.Printf("created a new branch %s\n", branch.Name)
+
+		if !switchToBranch {
+			return
+		}
+
+		if _, err := repo.CheckoutBranch(branch.Name); err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Printf("checkout to branch %s\n", branch.Name)
 	},
 }
 
@@ -95,9 +153,15 @@ var createDirectoryCmd = &cobra.Command{
 }
 
 var createReleaseCmd = &cobra.Command{
-	Use:   "release [releasename]",
-	Short: "Creates a release / tag",
-	Long:  ``,
+	Use:   "release [releasename] [notes]",
+	Short: "Creates a release",
+	Long: `Creates a release of the current commit. A release is an annotated tag whose
+  name matches the release pattern configured with releases.pattern in
+  .gong/config, by default v{version} where {version} is a semantic version.
+  Releasename can also be a bare version e.g. 1.2.3, which is formatted with
+  the release pattern. The release notes, channel and date are stored in the
+  tag message.`,
+	Args: cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
 		repo, err := gong.Open()
 		if err != nil {
@@ -106,28 +170,26 @@ var createReleaseCmd = &cobra.Command{
 		}
 		defer gong.Free(repo)
 
-		message := ""
+		notes := ""
 
 		if len(args) > 1 {
-			message = args[1]
+			notes = args[1]
 		}
 
-		tag, err := repo.CreateTag(args[0], message)
+		release, err := repo.CreateRelease(args[0], notes, releaseChannel)
 		if err != nil {
 			cmd.PrintErr(err)
 			return
 		}
+		defer gong.Free(release)
 
-		// TODO: Why cannot be freed from memory
-		// defer gong.Free(tag)
-
-		cmd.Printf("created a new release %s\n", tag.Name)
+		cmd.Printf("created a new release %s\n", release.Name)
 	},
 }
 
 var createTagCmd = &cobra.Command{
 	Use:   "tag [tagname]",
-	Short: "Creates a tag / release",
+	Short: "Creates a tag",
 	Long:  ``,
 	Args:  cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -156,3 +218,30 @@ var createTagCmd = &cobra.Command{
 		cmd.Printf("created a new tag %s\n", tag.Name)
 	},
 }
+
+func createFlags() {
+	createBranchCmd.Flags().StringVarP(
+		&branchType, "type", "t", "",
+		"Generate the branch name from the branch template of the given type",
+	)
+	createBranchCmd.Flags().StringVar(
+		&branchTicket, "ticket", "",
+		"Ticket identifier used in the generated branch name",
+	)
+	createBranchCmd.Flags().StringVar(
+		&branchFrom, "from", "",
+		"Start the branch from a branch, tag, release, remote branch or commit hash",
+	)
+	createBranchCmd.Flags().BoolVar(
+		&branchTrack, "track", false,
+		"Set the remote branch given with --from as upstream",
+	)
+	createBranchCmd.Flags().BoolVar(
+		&switchToBranch, "switch", false,
+		"Switch to the branch after it has been created",
+	)
+	createReleaseCmd.Flags().StringVar(
+		&releaseChannel, "channel", gong.DefaultReleaseChannel,
+		"Release channel e.g. stable, beta or nightly",
+	)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/erikjuhani/git-gong/config"
 	"github.com/erikjuhani/git-gong/gong"
 	lib "github.com/libgit2/git2go/v31"
 )
@@ -22,13 +23,21 @@ func contains(tags []*gong.Tag, tagname string) bool {
 
 func TestCreateBranchCmd(t *testing.T) {
 	tests := []struct {
-		name string
-		args []string
+		name     string
+		args     []string
+		expected string
 	}{
 		{
 			name: `Command gong create branch <branchname>.
 				Should create a new branch with <branchname>`,
-			args: []string{"gong-branch"},
+			args:     []string{"gong-branch"},
+			expected: "gong-branch",
+		},
+		{
+			name: `Command gong create branch --type <type> --ticket <ticket> <description>.
+				Should create a new branch with a name generated from the branch template.`,
+			args:     []string{"--type", "feature", "--ticket", "ABC-123", "Short description"},
+			expected: "feature/ABC-123-short-description",
 		},
 	}
 	repo, clean, err := gong.TestRepo()
@@ -64,12 +73,123 @@ func TestCreateBranchCmd(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			_, err = repo.FindBranch(tt.args[0], lib.BranchLocal)
+			_, err = repo.FindBranch(tt.expected, lib.BranchLocal)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	branchType, branchTicket = "", ""
+}
+
+func TestCreateBranchUnknownTypeCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	if _, err := repo.Seed(commitMsg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	config.BranchTemplates["feature"] = "feature/{ticket}-{description}"
+	defer delete(config.BranchTemplates, "feature")
+
+	defer func() { branchType, branchTicket = "", "" }()
+
+	t.Run(`Command gong create branch --type <unknown type> <description>.
+		Should refuse to create a branch when the type has no template.`, func(t *testing.T) {
+		rootCmd.SetArgs([]string{createCmd.Name(), createBranchCmd.Name(), "--type", "bugfix", "Short description"})
+		rootCmd.SetOut(bytes.NewBuffer(nil))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.FindBranch("bugfix/short-description", lib.BranchLocal); err == nil {
+			t.Fatal("expected no branch to be created for an unknown branch type")
+		}
+	})
+}
+
+func TestCreateBranchFromCmd(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: `Command gong create branch <branchname> --from <rev> --switch.
+				Should create a new branch with <branchname> starting from <rev>
+				and switch to it.`,
+			args: []string{"gong-branch", "--from", "v0.1.0", "--switch"},
+		},
+	}
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	firstCommit, err := repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedID := firstCommit.ID.String()
+
+	_, err = repo.CreateTag("v0.1.0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.Seed("second", "commit.me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workdir := repo.Path
+
+	if err := os.Chdir(workdir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{createCmd.Name(), createBranchCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			// Eat up the output
+			rootCmd.SetOut(bytes.NewBuffer(nil))
+
+			err = rootCmd.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			branch, err := repo.CurrentBranch()
 			if err != nil {
 				t.Fatal(err)
 			}
+
+			if branch.Name != tt.args[0] {
+				t.Fatal(fmt.Errorf("current branch %s does not equal to expected branch %s", branch.Name, tt.args[0]))
+			}
+
+			if branch.ReferenceID.String() != expectedID {
+				t.Fatal(fmt.Errorf("branch tip %s does not equal to expected tip %s", branch.ReferenceID.String(), expectedID))
+			}
 		})
 	}
+
+	branchFrom, switchToBranch = "", false
 }
 
 func TestCreateFileCmd(t *testing.T) {
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.AddCommand(
+		deleteBranchCmd,
+	)
+}
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete [subcommand]",
+	Short: "Delete branches.",
+	Long:  ``,
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var deleteBranchCmd = &cobra.Command{
+	Use:   "branch [branchname]",
+	Short: "Delete a local branch.",
+	Long: `Delete a local branch other than the current branch. Branches matching
+  protected branch patterns cannot be deleted. The deleted branch is recorded
+  and can be recovered with gong prune restore <branchname>. Without branchname
+  the branch can be picked interactively in a terminal.`,
+	Args: pickOr(cobra.ExactArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		if len(args) == 0 {
+			items, err := branchPickItems(repo, true, false)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			branchName, err := pickTarget(cmd, "delete branch", items)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			args = []string{branchName}
+		}
+
+		if err := repo.DeleteBranch(args[0]); err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Printf("deleted branch %s, restore it with gong prune restore %s\n", args[0], args[0])
+	},
+}
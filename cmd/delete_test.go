@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/gong"
+	lib "github.com/libgit2/git2go/v31"
+)
+
+func TestDeleteBranchCmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		branch    string
+		deleted   bool
+		shouldErr bool
+	}{
+		{
+			name: `Command gong delete branch <branchname>.
+				Should delete the branch and record it for restoring.`,
+			args:    []string{"gong-branch"},
+			branch:  "gong-branch",
+			deleted: true,
+		},
+		{
+			name: `Command gong delete branch <branchname>.
+				Should not delete the current branch.`,
+			args:    []string{"main"},
+			branch:  "main",
+			deleted: false,
+		},
+		{
+			name: `Command gong delete branch.
+				Should require branchname when not run in a terminal.`,
+			branch:    "main",
+			deleted:   false,
+			shouldErr: true,
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	_, err = repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.CreateLocalBranch("gong-branch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Input that is not a terminal disables the interactive picker
+	rootCmd.SetIn(bytes.NewBuffer(nil))
+	defer rootCmd.SetIn(nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{deleteCmd.Name(), deleteBranchCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			// Eat up the output
+			rootCmd.SetOut(bytes.NewBuffer(nil))
+			rootCmd.SetErr(bytes.NewBuffer(nil))
+			defer rootCmd.SetErr(nil)
+
+			err = rootCmd.Execute()
+			if (err != nil) != tt.shouldErr {
+				t.Fatal(fmt.Errorf("error %v, expected error %t", err, tt.shouldErr))
+			}
+
+			_, err = repo.FindBranch(tt.branch, lib.BranchLocal)
+			if deleted := err != nil; deleted != tt.deleted {
+				t.Fatal(fmt.Errorf("branch %s deleted %t, expected %t", tt.branch, deleted, tt.deleted))
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+
+	describeCmd.AddCommand(
+		describeBranchCmd,
+	)
+
+	describeFlags()
+}
+
+var (
+	branchOwner      string
+	branchMetaTicket string
+)
+
+var describeCmd = &cobra.Command{
+	Use:   "describe [subcommand]",
+	Short: "Describe branches.",
+	Long:  ``,
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var describeBranchCmd = &cobra.Command{
+	Use:   "branch [branchname] [description]",
+	Short: "Store a description, owner and linked ticket for a branch.",
+	Long: `Store a description, owner and linked ticket for branch with branchname.
+  Without a description and flags the current metadata of the branch is shown.
+
+  The metadata is stored to refs/gong/meta and survives branch renames.
+  Share the metadata with others by pushing the metadata reference e.g.
+  gong git push origin refs/gong/meta`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		branchName := args[0]
+
+		meta, err := repo.BranchMeta(branchName)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		if len(args) == 1 && branchOwner == "" && branchMetaTicket == "" {
+			cmd.Print(meta.String())
+			return
+		}
+
+		if len(args) > 1 {
+			meta.Description = args[1]
+		}
+
+		if branchOwner != "" {
+			meta.Owner = branchOwner
+		}
+
+		if branchMetaTicket != "" {
+			meta.Ticket = branchMetaTicket
+		}
+
+		if err := repo.SetBranchMeta(branchName, meta); err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Printf("described branch %s\n", branchName)
+	},
+}
+
+func describeFlags() {
+	describeBranchCmd.Flags().StringVar(
+		&branchOwner, "owner", "",
+		"Set the owner of the branch",
+	)
+	describeBranchCmd.Flags().StringVar(
+		&branchMetaTicket, "ticket", "",
+		"Set the ticket linked to the branch",
+	)
+}
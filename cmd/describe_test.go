@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/gong"
+)
+
+func TestDescribeBranchCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected gong.BranchMeta
+	}{
+		{
+			name: `Command gong describe branch <branchname> <description> --owner <owner> --ticket <ticket>.
+				Should store the description, owner and ticket for the branch.`,
+			args:     []string{"gong-branch", "Gong all the things", "--owner", "gong", "--ticket", "ABC-123"},
+			expected: gong.BranchMeta{Description: "Gong all the things", Owner: "gong", Ticket: "ABC-123"},
+		},
+		{
+			name: `Command gong describe branch <branchname> <description>.
+				Should store only the description for the branch.`,
+			args:     []string{"gong-description", "owner of all the things"},
+			expected: gong.BranchMeta{Description: "owner of all the things"},
+		},
+		{
+			name: `Command gong describe branch <branchname> --owner <owner>.
+				Should store only the owner for the branch.`,
+			args:     []string{"gong-owner", "--owner", "gong"},
+			expected: gong.BranchMeta{Owner: "gong"},
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	_, err = repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		if _, err := repo.CreateLocalBranch(tt.args[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	workdir := repo.Path
+
+	if err := os.Chdir(workdir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branchOwner, branchMetaTicket = "", ""
+
+			args := []string{describeCmd.Name(), describeBranchCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			// Eat up the output
+			rootCmd.SetOut(bytes.NewBuffer(nil))
+
+			err = rootCmd.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			meta, err := repo.BranchMeta(tt.args[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *meta != tt.expected {
+				t.Fatal(fmt.Errorf("branch metadata %v does not equal to expected %v", *meta, tt.expected))
+			}
+
+			// Metadata should survive a rename.
+			if _, err := repo.RenameBranch(tt.args[0], tt.args[0]+"-renamed"); err != nil {
+				t.Fatal(err)
+			}
+
+			meta, err = repo.BranchMeta(tt.args[0] + "-renamed")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *meta != tt.expected {
+				t.Fatal(fmt.Errorf("renamed branch metadata %v does not equal to expected %v", *meta, tt.expected))
+			}
+		})
+	}
+
+	branchOwner, branchMetaTicket = "", ""
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/erikjuhani/git-gong/gong"
+	lib "github.com/libgit2/git2go/v31"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.AddCommand(
+		listBranchesCmd,
+		listReleasesCmd,
+		listWorktreesCmd,
+	)
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list [subcommand]",
+	Short: "List branches, releases and worktrees.",
+	Long:  ``,
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var listBranchesCmd = &cobra.Command{
+	Use:   "branches",
+	Short: "List local branches with their descriptions.",
+	Long: `List local branches. The current branch is marked with an asterisk.
+  The upstream with the amount of commits ahead and behind is shown for branches
+  tracking an upstream. Branch description, owner and linked ticket are shown
+  when the branch has been described with gong describe branch.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		branches, err := repo.Branches(lib.BranchLocal)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		for _, branch := range branches {
+			head, err := branch.Essence().IsHead()
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			marker := " "
+			if head {
+				marker = "*"
+			}
+
+			meta, err := repo.BranchMeta(branch.Name)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			cmd.Printf("%s %s%s%s\n", marker, branch.Name, formatUpstream(branch), formatBranchMeta(meta))
+		}
+	},
+}
+
+var listReleasesCmd = &cobra.Command{
+	Use:   "releases",
+	Short: "List releases from the highest version to the lowest.",
+	Long: `List releases with their channel, date and the first line of the release
+  notes. The latest release is marked with an asterisk. Tags that do not match
+  the release pattern are not listed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		releases, err := repo.Releases()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		latest, err := repo.FindRelease(gong.LatestRelease)
+		if err != nil && len(releases) > 0 {
+			cmd.PrintErr(err)
+			return
+		}
+
+		for _, release := range releases {
+			marker := " "
+			if latest != nil && release.Name == latest.Name {
+				marker = "*"
+			}
+
+			notes := strings.SplitN(release.Notes, "\n", 2)[0]
+
+			line := fmt.Sprintf("%s %s  %s  %s  %s", marker, release.Name, release.Channel, release.Date.Format("2006-01-02"), notes)
+
+			cmd.Printf("%s\n", strings.TrimRight(line, " "))
+		}
+	},
+}
+
+var listWorktreesCmd = &cobra.Command{
+	Use:   "worktrees",
+	Short: "List the main worktree and the linked worktrees.",
+	Long: `List the worktrees with the branch or the detached commit checked out in
+  them. The current worktree is marked with an asterisk.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		worktrees, err := repo.Worktrees()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		for _, worktree := range worktrees {
+			marker := " "
+			if worktree.Current {
+				marker = "*"
+			}
+
+			checkout := worktree.Branch
+			if checkout == "" && len(worktree.Commit) >= 7 {
+				checkout = fmt.Sprintf("(detached %s)", worktree.Commit[:7])
+			}
+
+			var details []string
+			if worktree.IsMain() {
+				details = append(details, "main")
+			}
+			if worktree.Locked {
+				details = append(details, "locked")
+			}
+
+			line := fmt.Sprintf("%s %s  %s", marker, worktree.Path, checkout)
+			if len(details) > 0 {
+				line = fmt.Sprintf("%s  [%s]", line, strings.Join(details, ", "))
+			}
+
+			cmd.Printf("%s\n", line)
+		}
+	},
+}
+
+func formatUpstream(branch *gong.Branch) string {
+	if !branch.HasUpstream() {
+		return ""
+	}
+
+	ahead, behind, err := branch.AheadBehind()
+	if err != nil {
+		return fmt.Sprintf(" [%s]", branch.Upstream)
+	}
+
+	return fmt.Sprintf(" [%s ahead %d, behind %d]", branch.Upstream, ahead, behind)
+}
+
+func formatBranchMeta(meta *gong.BranchMeta) string {
+	if meta.Empty() {
+		return ""
+	}
+
+	var details []string
+
+	if meta.Owner != "" {
+		details = append(details, meta.Owner)
+	}
+
+	if meta.Ticket != "" {
+		details = append(details, meta.Ticket)
+	}
+
+	description := strings.SplitN(meta.Description, "\n", 2)[0]
+
+	if len(details) == 0 {
+		return fmt.Sprintf("  %s", description)
+	}
+
+	return fmt.Sprintf("  %s (%s)", description, strings.Join(details, ", "))
+}
@@ -1,19 +1,89 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/erikjuhani/git-gong/gong"
 	"github.com/spf13/cobra"
 )
 
 func init() {
 	rootCmd.AddCommand(mergeCmd)
+
+	mergeFlags()
 }
 
+var (
+	mergeContinue bool
+	mergeAbort    bool
+	mergeSquash   bool
+	mergeNoFF     bool
+	mergeFFOnly   bool
+	mergePreview  bool
+	mergeJSON     bool
+)
+
 var mergeCmd = &cobra.Command{
-	Use:   "merge [branchname]",
+	Use:   "merge [source]",
 	Short: "Merges the given branch to current branch",
-	Long:  ``,
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Merges the given source to current branch. The source is a local or a remote
+  branch, a tag, a release e.g. v1.2.0, latest or ^1.2, a commit hash or any
+  other revision. Without source a branch, tag, release or recent commit can be
+  picked interactively in a terminal.
+
+  If the branches conflict, the merge stops and leaves the conflict markers in
+  the working tree. The marker style is set with merge.conflict_style in
+  .gong/config to merge (default) or diff3, zdiff3 is not supported and is
+  refused. List the conflicted files with gong conflicts, resolve them and
+  create the merge commit with gong merge --continue, or restore the state
+  before the merge with gong merge --abort. Since aborting resets the working
+  tree, a merge that is not a fast-forward is refused when tracked files have
+  uncommitted changes.
+
+  By default the current branch is fast-forwarded when it has not diverged and
+  otherwise a merge commit is created. --no-ff always creates a merge commit,
+  --ff-only refuses to merge diverged branches and --squash squashes the
+  changes of the branch into a single commit. The default strategy of merges
+  into a branch can be configured in .gong/config, e.g.
+
+  [merge.strategies]
+  main = "squash"
+  "release/*" = "ff-only"
+
+  --preview runs the merge in memory without touching the index or the working
+  tree, and reports whether the branch would be fast-forwarded or merged, the
+  commits brought in, the changed files and the files that would conflict.
+  With --json the preview is printed as JSON.
+
+  Merges into a branch can be restricted with a merge policy in .gong/config.
+  sources lists the branch patterns that may be merged into the branch,
+  strategies the strategies that may be used and status_command a command that
+  has to succeed before the merge, e.g.
+
+  [merge.policies.main]
+  sources = ["feature/*", "fix/*"]
+  strategies = ["squash", "ff-only"]
+  status_command = "make test"
+
+  When sources are listed, tags, releases and commits cannot be merged. A
+  policy that cannot be read or has a pattern no branch name can match fails
+  loading the config. A * in the keys of merge.strategies and merge.policies
+  and in sources matches any characters, and a pattern matches the whole
+  branch name, e.g. "release/*" does not match old-release/1. When several keys match a branch, its exact name is used
+  first and then the pattern with the longest text before the first *, e.g.
+  "release/v1*" over "release/*".`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if mergeContinue || mergeAbort {
+			return cobra.NoArgs(cmd, args)
+		}
+
+		if mergeJSON && !mergePreview {
+			return fmt.Errorf("--json can only be used with --preview")
+		}
+
+		return pickOr(cobra.MinimumNArgs(1))(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		repo, err := gong.Open()
 		if err != nil {
@@ -22,8 +92,166 @@ var mergeCmd = &cobra.Command{
 		}
 		defer gong.Free(repo)
 
-		if err := repo.Merge(args[0]); err != nil {
+		if mergeAbort {
+			if err := repo.AbortMerge(); err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			cmd.Println("merge aborted")
+			return
+		}
+
+		if mergeContinue {
+			commit, err := repo.ContinueMerge()
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+			defer gong.Free(commit)
+
+			cmd.Printf("merged with commit %s\n", commit.ID.String())
+			return
+		}
+
+		if len(args) == 0 {
+			items, err := targetPickItems(repo)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			source, err := pickTarget(cmd, "merge", items)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			args = []string{source}
+		}
+
+		strategy, err := mergeStrategy()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		if mergePreview {
+			preview, err := repo.PreviewMerge(args[0], strategy)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			if err := printMergePreview(cmd, preview); err != nil {
+				cmd.PrintErr(err)
+			}
+			return
+		}
+
+		if err := repo.Merge(args[0], strategy); err != nil {
 			cmd.PrintErr(err)
 		}
 	},
 }
+
+func printMergePreview(cmd *cobra.Command, preview *gong.MergePreview) error {
+	if mergeJSON {
+		out, err := json.MarshalIndent(preview, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		cmd.Println(string(out))
+		return nil
+	}
+
+	cmd.Printf("%s into %s: %s\n", preview.Source, preview.Target, preview.Result)
+
+	if preview.Reason != "" {
+		cmd.Println(preview.Reason)
+	}
+
+	if preview.StatusCommand != "" {
+		cmd.Printf("status command: %s\n", preview.StatusCommand)
+	}
+
+	if len(preview.Commits) > 0 {
+		cmd.Printf("\nCommits (%d)\n", len(preview.Commits))
+		for _, commit := range preview.Commits {
+			cmd.Printf("  %s %s (%s)\n", commit.ID[:7], commit.Summary, commit.Author)
+		}
+	}
+
+	if len(preview.Files) > 0 {
+		cmd.Printf("\nFiles (%d)\n", len(preview.Files))
+		for _, file := range preview.Files {
+			cmd.Printf("  %s %s\n", file.Status, file.Path)
+		}
+	}
+
+	if len(preview.Conflicts) > 0 {
+		cmd.Printf("\nConflicts (%d)\n", len(preview.Conflicts))
+		for _, path := range preview.Conflicts {
+			cmd.Printf("  C %s\n", path)
+		}
+	}
+
+	return nil
+}
+
+// mergeStrategy returns the merge strategy selected with the flags, or an
+// empty strategy to use the configured default.
+func mergeStrategy() (gong.MergeStrategy, error) {
+	var strategies []gong.MergeStrategy
+
+	if mergeSquash {
+		strategies = append(strategies, gong.MergeSquash)
+	}
+	if mergeNoFF {
+		strategies = append(strategies, gong.MergeNoFastForward)
+	}
+	if mergeFFOnly {
+		strategies = append(strategies, gong.MergeFastForwardOnly)
+	}
+
+	switch len(strategies) {
+	case 0:
+		return "", nil
+	case 1:
+		return strategies[0], nil
+	default:
+		return "", fmt.Errorf("only one of --squash, --no-ff and --ff-only can be used")
+	}
+}
+
+func mergeFlags() {
+	mergeCmd.Flags().BoolVar(
+		&mergeContinue, "continue", false,
+		"Create the merge commit of a merge stopped by conflicts after the conflicts have been resolved",
+	)
+	mergeCmd.Flags().BoolVar(
+		&mergeAbort, "abort", false,
+		"Abort a merge stopped by conflicts and restore the state before the merge",
+	)
+	mergeCmd.Flags().BoolVar(
+		&mergeSquash, "squash", false,
+		"Squash the changes of the branch into a single commit",
+	)
+	mergeCmd.Flags().BoolVar(
+		&mergeNoFF, "no-ff", false,
+		"Create a merge commit even when the branch can be fast-forwarded",
+	)
+	mergeCmd.Flags().BoolVar(
+		&mergeFFOnly, "ff-only", false,
+		"Only fast-forward and refuse to merge diverged branches",
+	)
+	mergeCmd.Flags().BoolVar(
+		&mergePreview, "preview", false,
+		"Show what the merge would do without changing the index or the working tree",
+	)
+	mergeCmd.Flags().BoolVar(
+		&mergeJSON, "json", false,
+		"Print the preview as JSON",
+	)
+}
@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/erikjuhani/git-gong/config"
 	"github.com/erikjuhani/git-gong/gong"
 )
 
@@ -73,3 +79,889 @@ func TestMergeCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeConflictCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	commitFile := func(content string) {
+		if err := ioutil.WriteFile(filepath.Join(repo.Path, "a.file"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		tree, err := repo.AddToIndex([]string{"a.file"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.CreateCommit(tree, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := repo.Seed("default-commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile("gong-branch\n")
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile("main\n")
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	merge := func(args ...string) {
+		rootCmd.SetArgs(append([]string{mergeCmd.Name()}, args...))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func() {
+		mergeContinue = false
+		mergeAbort = false
+	}()
+
+	t.Run("Command gong merge <branchname> with uncommitted changes. Should refuse to merge and keep the changes", func(t *testing.T) {
+		readme := filepath.Join(repo.Path, "README.md")
+
+		if err := ioutil.WriteFile(readme, []byte("uncommitted\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		merge("gong-branch")
+
+		if repo.MergeInProgress() {
+			t.Fatal("expected merge to be refused")
+		}
+
+		content, err := ioutil.ReadFile(readme)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(content) != "uncommitted\n" {
+			t.Fatalf("expected the uncommitted changes to be kept, got %q", content)
+		}
+
+		if err := ioutil.WriteFile(readme, []byte("temp\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Command gong merge <branchname> with conflicts. Should leave the conflicts in place", func(t *testing.T) {
+		merge("gong-branch")
+
+		conflicts, err := repo.Conflicts()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(conflicts) != 1 || conflicts[0] != "a.file" {
+			t.Fatalf("expected a.file to be conflicted, got %v", conflicts)
+		}
+
+		if !repo.MergeInProgress() {
+			t.Fatal("expected merge to be in progress")
+		}
+	})
+
+	t.Run("Command gong merge --abort. Should restore the state before the merge", func(t *testing.T) {
+		merge("--abort")
+		mergeAbort = false
+
+		if repo.MergeInProgress() {
+			t.Fatal("expected merge to be aborted")
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(repo.Path, "a.file"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(content) != "main\n" {
+			t.Fatalf("expected a.file to be restored, got %q", content)
+		}
+	})
+
+	t.Run("Command gong merge --continue. Should create the merge commit", func(t *testing.T) {
+		merge("gong-branch")
+
+		if err := ioutil.WriteFile(filepath.Join(repo.Path, "a.file"), []byte("resolved\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		merge("--continue")
+		mergeContinue = false
+
+		if repo.MergeInProgress() {
+			t.Fatal("expected merge to be finished")
+		}
+
+		head, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(head)
+
+		if head.Essence().ParentCount() != 2 {
+			t.Fatalf("expected a merge commit with 2 parents, got %d", head.Essence().ParentCount())
+		}
+	})
+}
+
+func TestMergeStrategyCmd(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		flag        *bool
+		strategies  map[string]string
+		diverged    bool
+		parents     uint
+		fastForward bool
+		merged      bool
+	}{
+		{
+			name:        "Command gong merge --ff-only <branchname>. Should fast-forward the current branch",
+			args:        []string{"--ff-only", "gong-branch"},
+			flag:        &mergeFFOnly,
+			parents:     1,
+			fastForward: true,
+			merged:      true,
+		},
+		{
+			name:     "Command gong merge --ff-only <branchname> with diverged branches. Should refuse to merge",
+			args:     []string{"--ff-only", "gong-branch"},
+			flag:     &mergeFFOnly,
+			diverged: true,
+			parents:  1,
+		},
+		{
+			name:    "Command gong merge --no-ff <branchname>. Should create a merge commit",
+			args:    []string{"--no-ff", "gong-branch"},
+			flag:    &mergeNoFF,
+			parents: 2,
+			merged:  true,
+		},
+		{
+			name:    "Command gong merge --squash <branchname>. Should squash the changes into a single commit",
+			args:    []string{"--squash", "gong-branch"},
+			flag:    &mergeSquash,
+			parents: 1,
+			merged:  true,
+		},
+		{
+			name:       "Command gong merge <branchname> with a default strategy configured for the branch. Should merge with the strategy",
+			args:       []string{"gong-branch"},
+			strategies: map[string]string{"main": "squash"},
+			parents:    1,
+			merged:     true,
+		},
+		{
+			name:       "Command gong merge <branchname> with a default strategy configured for a branch pattern. Should merge with the strategy",
+			args:       []string{"gong-branch"},
+			strategies: map[string]string{"mai*": "no-ff"},
+			parents:    2,
+			merged:     true,
+		},
+		{
+			name:       "Command gong merge <branchname> with default strategies configured for several matching branch patterns. Should merge with the strategy of the most specific pattern",
+			args:       []string{"gong-branch"},
+			strategies: map[string]string{"*": "squash", "ma*": "no-ff"},
+			parents:    2,
+			merged:     true,
+		},
+		{
+			name:        "Command gong merge <branchname> with default strategies configured for patterns matching only a part of the branch name. Should not use the strategies",
+			args:        []string{"gong-branch"},
+			strategies:  map[string]string{"ai": "no-ff", "x*main": "squash", "main/*": "no-ff", "mai": "squash"},
+			parents:     1,
+			fastForward: true,
+			merged:      true,
+		},
+		{
+			name:        "Command gong merge --ff-only <branchname> with a default strategy configured for the branch. Should prefer the flag",
+			args:        []string{"--ff-only", "gong-branch"},
+			flag:        &mergeFFOnly,
+			strategies:  map[string]string{"main": "squash"},
+			parents:     1,
+			fastForward: true,
+			merged:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.flag != nil {
+				defer func() { *tt.flag = false }()
+			}
+
+			for branchName, strategy := range tt.strategies {
+				config.MergeStrategies[branchName] = strategy
+				defer delete(config.MergeStrategies, branchName)
+			}
+
+			repo, clean, err := gong.TestRepo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clean()
+
+			if _, err := repo.Seed("default-commit"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+				t.Fatal(err)
+			}
+
+			branchCommit, err := repo.Seed("gong-branch-commit", "a.file")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CheckoutBranch("main"); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.diverged {
+				if _, err := repo.Seed("main-commit", "b.file"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			before, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Chdir(repo.Path); err != nil {
+				t.Fatal(err)
+			}
+
+			rootCmd.SetArgs(append([]string{mergeCmd.Name()}, tt.args...))
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			branch, err := repo.Head.Branch()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if branch.Name != "main" {
+				t.Fatalf("expected to stay on branch main, got %s", branch.Name)
+			}
+
+			head, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if head.Essence().ParentCount() != tt.parents {
+				t.Fatalf("expected head to have %d parents, got %d", tt.parents, head.Essence().ParentCount())
+			}
+
+			if tt.fastForward != (head.ID.String() == branchCommit.ID.String()) {
+				t.Fatalf("expected fast-forward %t, head is %s", tt.fastForward, head.ID.String())
+			}
+
+			if !tt.merged && head.ID.String() != before.ID.String() {
+				t.Fatalf("expected head to stay at %s, got %s", before.ID.String(), head.ID.String())
+			}
+
+			if _, err := os.Stat(filepath.Join(repo.Path, "a.file")); tt.merged != (err == nil) {
+				t.Fatalf("expected a.file to exist %t", tt.merged)
+			}
+		})
+	}
+}
+
+func TestMergeSourceCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  func(commit *gong.Commit) string
+		message string
+	}{
+		{
+			name:    "Command gong merge --no-ff <tag>. Should merge the tag",
+			source:  func(*gong.Commit) string { return "gong-tag" },
+			message: "Merge tag gong-tag into main",
+		},
+		{
+			name:    "Command gong merge --no-ff <release>. Should merge the release",
+			source:  func(*gong.Commit) string { return "latest" },
+			message: "Merge release v1.0.0 into main",
+		},
+		{
+			name:    "Command gong merge --no-ff <tag> with a lightweight tag named like a release. Should merge the tag, it is not a release",
+			source:  func(*gong.Commit) string { return "v2.0.0" },
+			message: "Merge tag v2.0.0 into main",
+		},
+		{
+			name:    "Command gong merge --no-ff <hash>. Should merge the commit",
+			source:  func(commit *gong.Commit) string { return commit.ID.String()[:7] },
+			message: "Merge commit %s into main",
+		},
+		{
+			name:    "Command gong merge --no-ff <revision>. Should merge the commit the revision resolves to",
+			source:  func(*gong.Commit) string { return "gong-branch~0" },
+			message: "Merge commit %s into main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { mergeNoFF = false }()
+
+			repo, clean, err := gong.TestRepo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clean()
+
+			if _, err := repo.Seed("default-commit"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+				t.Fatal(err)
+			}
+
+			commit, err := repo.Seed("gong-branch-commit", "a.file")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CreateTag("gong-tag", "gong-tag"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CreateRelease("1.0.0", "", ""); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.Essence().Tags.CreateLightweight("v2.0.0", commit.Essence(), false); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CheckoutBranch("main"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Chdir(repo.Path); err != nil {
+				t.Fatal(err)
+			}
+
+			rootCmd.SetArgs([]string{mergeCmd.Name(), "--no-ff", tt.source(commit)})
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			head, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if head.Essence().ParentCount() != 2 || head.Essence().ParentId(1).String() != commit.ID.String() {
+				t.Fatalf("expected a merge commit of %s", commit.ID.String())
+			}
+
+			expected := tt.message
+			if strings.Contains(expected, "%s") {
+				expected = fmt.Sprintf(expected, commit.ID.String()[:7])
+			}
+
+			if strings.TrimSpace(head.Message) != expected {
+				t.Fatalf("expected merge message %q, got %q", expected, head.Message)
+			}
+		})
+	}
+}
+
+func TestMergeRemoteSourceCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		branch  string
+		message string
+	}{
+		{
+			name:    "Command gong merge --no-ff <remote>/<branch>. Should merge the remote branch",
+			source:  "origin/main",
+			branch:  "main",
+			message: "Merge remote branch origin/main into main",
+		},
+		{
+			name: `Command gong merge --no-ff <branch>. Should merge the remote branch
+				when <branch> does not exist locally and exists on one remote only`,
+			source:  "gong-branch",
+			branch:  "gong-branch",
+			message: "Merge remote branch origin/gong-branch into main",
+		},
+		{
+			name: `Command gong merge --no-ff <branch>. Should not merge when <branch>
+				does not exist locally and exists on more than one remote`,
+			source: "shared-branch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { mergeNoFF = false }()
+
+			upstream, clean, err := gong.TestRepo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clean()
+
+			if _, err := upstream.Seed("default-commit"); err != nil {
+				t.Fatal(err)
+			}
+
+			dir, err := ioutil.TempDir("", "gong-clone")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			clonePath := filepath.Join(dir, "clone")
+
+			if _, err := gong.Clone(upstream.GitPath, clonePath); err != nil {
+				t.Fatal(err)
+			}
+
+			remoteCommits := make(map[string]*gong.Commit)
+
+			if remoteCommits["main"], err = upstream.Seed("main-commit", "b.file"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := upstream.CheckoutBranch("gong-branch"); err != nil {
+				t.Fatal(err)
+			}
+
+			if remoteCommits["gong-branch"], err = upstream.Seed("gong-branch-commit", "a.file"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Chdir(clonePath); err != nil {
+				t.Fatal(err)
+			}
+
+			repo, err := gong.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer gong.Free(repo)
+
+			origin, err := repo.Essence().Remotes.Lookup("origin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer origin.Free()
+
+			if err := origin.Fetch(nil, nil, ""); err != nil {
+				t.Fatal(err)
+			}
+
+			other, err := repo.Essence().Remotes.Create("other", "https://example.com/other/gong.git")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer other.Free()
+
+			before, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, remoteName := range []string{"origin", "other"} {
+				_, err = repo.Essence().References.Create(fmt.Sprintf("refs/remotes/%s/shared-branch", remoteName), before.ID, false, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			errOut := bytes.NewBuffer(nil)
+			rootCmd.SetErr(errOut)
+			defer rootCmd.SetErr(nil)
+
+			rootCmd.SetArgs([]string{mergeCmd.Name(), "--no-ff", tt.source})
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			head, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.branch == "" {
+				if head.ID.String() != before.ID.String() {
+					t.Fatalf("expected %s not to be merged", tt.source)
+				}
+
+				if !strings.Contains(errOut.String(), gong.ErrAmbiguousRemoteBranch.Error()) {
+					t.Fatalf("expected the ambiguous remote branch error, got %q", errOut.String())
+				}
+
+				return
+			}
+
+			remoteCommit := remoteCommits[tt.branch]
+
+			if head.Essence().ParentCount() != 2 || head.Essence().ParentId(1).String() != remoteCommit.ID.String() {
+				t.Fatalf("expected a merge commit of %s", remoteCommit.ID.String())
+			}
+
+			if strings.TrimSpace(head.Message) != tt.message {
+				t.Fatalf("expected merge message %q, got %q", tt.message, head.Message)
+			}
+		})
+	}
+}
+
+func TestMergePreviewCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	commitFile := func(file string, content string) {
+		if err := ioutil.WriteFile(filepath.Join(repo.Path, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		tree, err := repo.AddToIndex([]string{file})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.CreateCommit(tree, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := repo.Seed("default-commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile("a.file", "gong-branch\n")
+	commitFile("b.file", "gong-branch\n")
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile("a.file", "main\n")
+
+	if _, err := repo.CheckoutBranch("ff-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile("c.file", "ff-branch\n")
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := repo.Head.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gong.Free(before)
+
+	defer func() {
+		mergePreview = false
+		mergeJSON = false
+		mergeFFOnly = false
+	}()
+
+	runPreview := func(args ...string) string {
+		out := bytes.NewBuffer(nil)
+		rootCmd.SetOut(out)
+		defer rootCmd.SetOut(nil)
+
+		rootCmd.SetArgs(append([]string{mergeCmd.Name()}, args...))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+
+		return out.String()
+	}
+
+	previewJSON := func(args ...string) gong.MergePreview {
+		out := runPreview(append(args, "--preview", "--json")...)
+
+		var preview gong.MergePreview
+		if err := json.Unmarshal([]byte(out), &preview); err != nil {
+			t.Fatalf("expected JSON output, got %q: %v", out, err)
+		}
+
+		return preview
+	}
+
+	expectUnchanged := func() {
+		after, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(after)
+
+		if !before.ID.Equal(after.ID) {
+			t.Fatal("expected head to be unchanged by the preview")
+		}
+	}
+
+	t.Run("Command gong merge <branchname> --preview --json. Should report the conflicts without merging", func(t *testing.T) {
+		preview := previewJSON("gong-branch")
+
+		if preview.Result != gong.MergeResultMerge {
+			t.Fatalf("expected result %s, got %s", gong.MergeResultMerge, preview.Result)
+		}
+
+		if len(preview.Commits) != 2 {
+			t.Fatalf("expected 2 commits, got %v", preview.Commits)
+		}
+
+		if len(preview.Conflicts) != 1 || preview.Conflicts[0] != "a.file" {
+			t.Fatalf("expected a.file to conflict, got %v", preview.Conflicts)
+		}
+
+		if len(preview.Files) != 1 || preview.Files[0].Path != "b.file" || preview.Files[0].Status != "A" {
+			t.Fatalf("expected b.file to be added, got %v", preview.Files)
+		}
+
+		expectUnchanged()
+
+		if repo.MergeInProgress() {
+			t.Fatal("expected no merge to be in progress")
+		}
+
+		conflicts, err := repo.Conflicts()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(conflicts) != 0 {
+			t.Fatalf("expected the index to be untouched, got conflicts %v", conflicts)
+		}
+	})
+
+	t.Run("Command gong merge <branchname> --preview. Should print the preview as text", func(t *testing.T) {
+		mergeJSON = false
+
+		out := runPreview("gong-branch", "--preview")
+
+		for _, expected := range []string{
+			"branch gong-branch into main: merge\n",
+			"\nCommits (2)\n",
+			"\nFiles (1)\n  A b.file\n",
+			"\nConflicts (1)\n  C a.file\n",
+		} {
+			if !strings.Contains(out, expected) {
+				t.Fatalf("expected the preview to contain %q, got %q", expected, out)
+			}
+		}
+
+		expectUnchanged()
+	})
+
+	t.Run("Command gong merge <branchname> --preview --json. Should report a fast-forward with the files it changes", func(t *testing.T) {
+		preview := previewJSON("ff-branch")
+
+		if preview.Result != gong.MergeResultFastForward {
+			t.Fatalf("expected result %s, got %s", gong.MergeResultFastForward, preview.Result)
+		}
+
+		if len(preview.Commits) != 1 {
+			t.Fatalf("expected 1 commit, got %v", preview.Commits)
+		}
+
+		if len(preview.Files) != 1 || preview.Files[0].Path != "c.file" || preview.Files[0].Status != "A" {
+			t.Fatalf("expected c.file to be added, got %v", preview.Files)
+		}
+
+		if len(preview.Conflicts) != 0 {
+			t.Fatalf("expected no conflicts, got %v", preview.Conflicts)
+		}
+
+		expectUnchanged()
+	})
+
+	t.Run("Command gong merge <branchname> --preview --json --ff-only. Should report the merge as refused when the branches have diverged", func(t *testing.T) {
+		preview := previewJSON("gong-branch", "--ff-only")
+
+		if preview.Result != gong.MergeResultRefused {
+			t.Fatalf("expected result %s, got %s", gong.MergeResultRefused, preview.Result)
+		}
+
+		if preview.Strategy != gong.MergeFastForwardOnly {
+			t.Fatalf("expected strategy %s, got %s", gong.MergeFastForwardOnly, preview.Strategy)
+		}
+
+		if !strings.Contains(preview.Reason, "diverged") {
+			t.Fatalf("expected the reason to explain the branches have diverged, got %q", preview.Reason)
+		}
+
+		expectUnchanged()
+	})
+}
+
+func TestMergePolicyCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	if _, err := repo.Seed("default-commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	branchFiles := map[string]string{
+		"gong-branch":      "a.file",
+		"feature/a":        "b.file",
+		"evil-feature/x":   "c.file",
+		"hotfix-feature/y": "d.file",
+	}
+
+	for branchName, file := range branchFiles {
+		if _, err := repo.CheckoutBranch(branchName); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.Seed(branchName+"-commit", file); err != nil {
+			t.Fatal(err)
+		}
+
+		// A tag named like an allowed branch is not a branch.
+		if branchName == "gong-branch" {
+			if _, err := repo.CreateTag("feature/tag", "feature/tag"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := repo.CheckoutBranch("main"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := config.MergePolicy{
+		Sources:    []string{"feature/*"},
+		Strategies: []string{"ff-only"},
+	}
+
+	defer delete(config.MergePolicies, "main")
+
+	tests := []struct {
+		name          string
+		args          []string
+		statusCommand string
+		merged        bool
+	}{
+		{
+			name:          "Command gong merge <branchname> from a source the policy does not allow. Should refuse the merge",
+			args:          []string{"gong-branch"},
+			statusCommand: "true",
+		},
+		{
+			name:          "Command gong merge <branchname> from a source only ending like an allowed pattern. Should refuse the merge",
+			args:          []string{"evil-feature/x"},
+			statusCommand: "true",
+		},
+		{
+			name:          "Command gong merge <branchname> from a source containing an allowed pattern. Should refuse the merge",
+			args:          []string{"hotfix-feature/y"},
+			statusCommand: "true",
+		},
+		{
+			name:          "Command gong merge <tag> with a tag named like an allowed pattern. Should refuse the merge",
+			args:          []string{"feature/tag"},
+			statusCommand: "true",
+		},
+		{
+			name:          "Command gong merge <branchname> --no-ff with a strategy the policy does not allow. Should refuse the merge",
+			args:          []string{"feature/a", "--no-ff"},
+			statusCommand: "true",
+		},
+		{
+			name:          "Command gong merge <branchname> with a failing status command. Should refuse the merge",
+			args:          []string{"feature/a"},
+			statusCommand: "exit 1",
+		},
+		{
+			name:          "Command gong merge <branchname> allowed by the policy. Should fast-forward the branch",
+			args:          []string{"feature/a"},
+			statusCommand: "true",
+			merged:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { mergeNoFF = false }()
+
+			policy.StatusCommand = tt.statusCommand
+			config.MergePolicies["main"] = policy
+
+			before, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer gong.Free(before)
+
+			rootCmd.SetArgs(append([]string{mergeCmd.Name()}, tt.args...))
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			after, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer gong.Free(after)
+
+			if merged := !before.ID.Equal(after.ID); merged != tt.merged {
+				t.Fatalf("expected merged to be %t, got %t", tt.merged, merged)
+			}
+
+			if tt.merged && after.Essence().ParentCount() != 1 {
+				t.Fatal("expected the branch to be fast-forwarded")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(pickCmd)
+
+	pickFlags()
+}
+
+var (
+	pickContinue     bool
+	pickAbort        bool
+	pickRecordOrigin bool
+	pickMainline     uint
+)
+
+var pickCmd = &cobra.Command{
+	Use:   "pick [revision...]",
+	Short: "Applies the changes of commits onto the current branch.",
+	Long: `Applies the changes of the commits as new commits on top of the current
+  branch, oldest first. A revision is a single revision or a range a..b of the
+  commits reachable from b but not from a, e.g. main~3..main.
+
+  Merge commits are picked relative to the mainline parent selected with
+  --mainline. With -x the message of the new commit refers to the picked commit.
+
+  If a commit conflicts, the pick stops and leaves the conflict markers in the
+  working tree. Resolve the conflicts and carry on with gong pick --continue, or
+  return to the state before the pick with gong pick --abort. Commits cannot be
+  picked onto a protected branch.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if pickContinue || pickAbort {
+			return cobra.NoArgs(cmd, args)
+		}
+
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		if pickAbort {
+			if err := repo.AbortPick(); err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			cmd.Println("pick aborted")
+			return
+		}
+
+		if pickContinue {
+			if err := repo.ContinuePick(); err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			cmd.Println("pick finished")
+			return
+		}
+
+		picked, err := repo.Pick(args, pickMainline, pickRecordOrigin)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Printf("picked %d commits\n", picked)
+	},
+}
+
+func pickFlags() {
+	pickCmd.Flags().BoolVar(
+		&pickContinue, "continue", false,
+		"Commit the resolved conflicts of a stopped pick and pick the rest of the commits",
+	)
+	pickCmd.Flags().BoolVar(
+		&pickAbort, "abort", false,
+		"Abort a stopped pick and return to the state before the pick",
+	)
+	pickCmd.Flags().BoolVarP(
+		&pickRecordOrigin, "record-origin", "x", false,
+		"Refer to the picked commit in the message of the new commit",
+	)
+	pickCmd.Flags().UintVarP(
+		&pickMainline, "mainline", "m", 0,
+		"The parent number, starting from 1, merge commits are picked relative to",
+	)
+}
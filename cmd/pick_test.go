@@ -0,0 +1,476 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/config"
+	"github.com/erikjuhani/git-gong/gong"
+)
+
+func TestPickCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		commits int
+		files   []string
+		missing []string
+		applied []string
+		origin  bool
+	}{
+		{
+			name:    "Command gong pick <revision>. Should apply the changes of the commit onto the current branch",
+			args:    []string{"gong-branch"},
+			commits: 1,
+			files:   []string{"b.file"},
+			missing: []string{"a.file"},
+		},
+		{
+			name:    "Command gong pick <a..b>. Should apply the changes of the commits in the range",
+			args:    []string{"main..gong-branch"},
+			commits: 2,
+			files:   []string{"a.file", "b.file"},
+		},
+		{
+			name:    "Command gong pick -x <revision>. Should refer to the picked commit in the message",
+			args:    []string{"-x", "gong-branch~1"},
+			commits: 1,
+			files:   []string{"a.file"},
+			missing: []string{"b.file"},
+			origin:  true,
+		},
+		{
+			name:    "Command gong pick <a..b> with changes already applied. Should only count the created commits",
+			args:    []string{"main..gong-branch"},
+			commits: 1,
+			files:   []string{"a.file", "b.file"},
+			applied: []string{"a.file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { pickRecordOrigin = false }()
+
+			repo, clean, err := gong.TestRepo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clean()
+
+			if _, err := repo.Seed("default-commit"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.Seed("a-commit", "a.file"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.Seed("b-commit", "b.file"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CheckoutBranch("main"); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, file := range tt.applied {
+				if _, err := repo.Seed("applied-commit", file); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			before, err := repo.Seed("main-commit", "c.file")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Chdir(repo.Path); err != nil {
+				t.Fatal(err)
+			}
+
+			out := bytes.NewBuffer(nil)
+			rootCmd.SetOut(out)
+			defer rootCmd.SetOut(nil)
+
+			rootCmd.SetArgs(append([]string{pickCmd.Name()}, tt.args...))
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			if expected := fmt.Sprintf("picked %d commits", tt.commits); !strings.Contains(out.String(), expected) {
+				t.Fatalf("expected %q, got %q", expected, out.String())
+			}
+
+			head, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			commit := head
+			for i := 0; i < tt.commits; i++ {
+				commit = commit.Parent()
+			}
+
+			if commit.ID.String() != before.ID.String() {
+				t.Fatal(fmt.Errorf("expected %d commits to be picked onto %s", tt.commits, before.ID.String()))
+			}
+
+			for _, file := range tt.files {
+				if _, err := os.Stat(filepath.Join(repo.Path, file)); err != nil {
+					t.Fatalf("expected %s to exist", file)
+				}
+			}
+
+			for _, file := range tt.missing {
+				if _, err := os.Stat(filepath.Join(repo.Path, file)); err == nil {
+					t.Fatalf("expected %s not to exist", file)
+				}
+			}
+
+			if tt.origin != strings.Contains(head.Message, "(cherry picked from commit ") {
+				t.Fatalf("expected the origin to be recorded %t, got message %q", tt.origin, head.Message)
+			}
+		})
+	}
+}
+
+func TestPickConflictCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	commitFile := func(content string) *gong.Commit {
+		if err := ioutil.WriteFile(filepath.Join(repo.Path, "a.file"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		tree, err := repo.AddToIndex([]string{"a.file"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		commit, err := repo.CreateCommit(tree, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return commit
+	}
+
+	if _, err := repo.Seed("default-commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile("gong-branch\n")
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	before := commitFile("main\n")
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	pick := func(args ...string) {
+		rootCmd.SetArgs(append([]string{pickCmd.Name()}, args...))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func() {
+		pickContinue = false
+		pickAbort = false
+	}()
+
+	t.Run("Command gong pick <revision> with conflicts. Should stop with the conflicts in place", func(t *testing.T) {
+		pick("gong-branch")
+
+		inProgress, err := repo.PickInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !inProgress {
+			t.Fatal("expected pick to be in progress")
+		}
+
+		conflicts, err := repo.Conflicts()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(conflicts) != 1 || conflicts[0] != "a.file" {
+			t.Fatalf("expected a.file to be conflicted, got %v", conflicts)
+		}
+	})
+
+	t.Run("Command gong pick --abort. Should return to the state before the pick", func(t *testing.T) {
+		pick("--abort")
+		pickAbort = false
+
+		inProgress, err := repo.PickInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if inProgress {
+			t.Fatal("expected pick to be aborted")
+		}
+
+		head, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(head)
+
+		if !head.ID.Equal(before.ID) {
+			t.Fatalf("expected head to be %s, got %s", before.ID.String(), head.ID.String())
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(repo.Path, "a.file"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(content) != "main\n" {
+			t.Fatalf("expected a.file to be restored, got %q", content)
+		}
+	})
+
+	t.Run("Command gong pick --continue. Should commit the resolution and finish the pick", func(t *testing.T) {
+		pick("gong-branch")
+
+		if err := ioutil.WriteFile(filepath.Join(repo.Path, "a.file"), []byte("resolved\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		pick("--continue")
+		pickContinue = false
+
+		inProgress, err := repo.PickInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if inProgress {
+			t.Fatal("expected pick to be finished")
+		}
+
+		branch, err := repo.Head.Branch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(branch)
+
+		if branch.Name != "main" {
+			t.Fatalf("expected to be on branch main, got %s", branch.Name)
+		}
+
+		head, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(head)
+
+		if head.Parent().ID.String() != before.ID.String() {
+			t.Fatalf("expected the commit to be picked onto %s, got %s", before.ID.String(), head.Parent().ID.String())
+		}
+
+		if head.Message != "gong-branch\n" {
+			t.Fatalf("expected the message of the picked commit to be kept, got %q", head.Message)
+		}
+	})
+}
+
+func TestPickMainlineCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		mainline bool
+		picked   bool
+	}{
+		{
+			name:     "Command gong pick -m 1 <merge commit>. Should apply the changes of the merge relative to the first parent",
+			mainline: true,
+			picked:   true,
+		},
+		{
+			name: "Command gong pick <merge commit> without a mainline. Should refuse to pick",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { pickMainline = 0 }()
+
+			repo, clean, err := gong.TestRepo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clean()
+
+			if _, err := repo.Seed("default-commit"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CreateLocalBranch("target"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.Seed("a-commit", "a.file"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CheckoutBranch("main"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.Seed("main-commit", "c.file"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := repo.Merge("gong-branch", gong.MergeNoFastForward); err != nil {
+				t.Fatal(err)
+			}
+
+			merge, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer gong.Free(merge)
+
+			if _, err := repo.CheckoutBranch("target"); err != nil {
+				t.Fatal(err)
+			}
+
+			before, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer gong.Free(before)
+
+			if err := os.Chdir(repo.Path); err != nil {
+				t.Fatal(err)
+			}
+
+			args := []string{pickCmd.Name(), merge.ID.String()}
+			if tt.mainline {
+				args = append(args, "-m", "1")
+			}
+
+			rootCmd.SetArgs(args)
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			head, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer gong.Free(head)
+
+			if !tt.picked {
+				if !head.ID.Equal(before.ID) {
+					t.Fatalf("expected head to stay at %s, got %s", before.ID.String(), head.ID.String())
+				}
+				return
+			}
+
+			if head.Essence().ParentCount() != 1 || head.Parent().ID.String() != before.ID.String() {
+				t.Fatalf("expected a single commit to be picked onto %s", before.ID.String())
+			}
+
+			if _, err := os.Stat(filepath.Join(repo.Path, "a.file")); err != nil {
+				t.Fatal("expected the changes of the merged branch to be picked")
+			}
+
+			if _, err := os.Stat(filepath.Join(repo.Path, "c.file")); err == nil {
+				t.Fatal("expected the changes of the first parent not to be picked")
+			}
+		})
+	}
+}
+
+func TestPickProtectedBranchCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	if _, err := repo.Seed("default-commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Seed("a-commit", "a.file"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := repo.Head.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gong.Free(before)
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	config.ProtectedBranchPatterns.AddPattern("main")
+	defer func() { *config.ProtectedBranchPatterns = config.Patterns{} }()
+
+	t.Run("Command gong pick <revision> on a protected branch. Should refuse to pick", func(t *testing.T) {
+		rootCmd.SetArgs([]string{pickCmd.Name(), "gong-branch"})
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+
+		head, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(head)
+
+		if !head.ID.Equal(before.ID) {
+			t.Fatalf("expected head to stay at %s, got %s", before.ID.String(), head.ID.String())
+		}
+
+		if _, err := os.Stat(filepath.Join(repo.Path, "a.file")); err == nil {
+			t.Fatal("expected a.file not to be picked")
+		}
+	})
+}
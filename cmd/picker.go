@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/erikjuhani/git-gong/cli"
+	"github.com/erikjuhani/git-gong/gong"
+	lib "github.com/libgit2/git2go/v31"
+	"github.com/spf13/cobra"
+)
+
+// pickOr returns a positional argument validator that accepts no arguments
+// when the target can be picked interactively, and otherwise falls back to
+// the given validator.
+func pickOr(validator cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && canPick(cmd) {
+			return nil
+		}
+
+		return validator(cmd, args)
+	}
+}
+
+// canPick reports whether the command reads its input from a terminal.
+func canPick(cmd *cobra.Command) bool {
+	in, ok := cmd.InOrStdin().(*os.File)
+	return ok && cli.IsTerminal(in)
+}
+
+// pickTarget lets the user pick one of the items interactively and returns
+// the value of the item.
+func pickTarget(cmd *cobra.Command, prompt string, items []cli.PickItem) (string, error) {
+	in, ok := cmd.InOrStdin().(*os.File)
+	if !ok {
+		return "", fmt.Errorf("interactive picker requires a terminal")
+	}
+
+	item, err := cli.Pick(in, cmd.ErrOrStderr(), prompt, items)
+	if err != nil {
+		return "", err
+	}
+
+	if item.Value != "" {
+		return item.Value, nil
+	}
+
+	return item.Label, nil
+}
+
+// branchPickItems returns the local branches as pick items. The current
+// branch is left out when skipCurrent is true. With remotes the remote
+// branches without a local branch of the same name are included too, labeled
+// with the remote. Picking a remote branch gives its name without the remote
+// when no other remote has a branch of the same name.
+func branchPickItems(repo *gong.Repository, skipCurrent bool, remotes bool) ([]cli.PickItem, error) {
+	branches, err := repo.Branches(lib.BranchLocal)
+	if err != nil {
+		return nil, err
+	}
+	defer freeBranches(branches)
+
+	var items []cli.PickItem
+	local := make(map[string]bool)
+
+	for _, branch := range branches {
+		local[branch.Name] = true
+
+		head, err := branch.Essence().IsHead()
+		if err != nil {
+			return nil, err
+		}
+
+		if head && skipCurrent {
+			continue
+		}
+
+		items = append(items, cli.PickItem{
+			Label:   branch.Name,
+			Kind:    "branch",
+			Preview: commitPreview(repo, branch.ReferenceID),
+		})
+	}
+
+	if !remotes {
+		return items, nil
+	}
+
+	remoteBranches, err := repo.Branches(lib.BranchRemote)
+	if err != nil {
+		return nil, err
+	}
+	defer freeBranches(remoteBranches)
+
+	remoteCount := make(map[string]int)
+
+	for _, branch := range remoteBranches {
+		if parts := strings.SplitN(branch.Name, "/", 2); len(parts) == 2 {
+			remoteCount[parts[1]]++
+		}
+	}
+
+	for _, branch := range remoteBranches {
+		parts := strings.SplitN(branch.Name, "/", 2)
+		if len(parts) != 2 || parts[1] == "HEAD" || local[parts[1]] {
+			continue
+		}
+
+		value := parts[1]
+		if remoteCount[value] > 1 {
+			value = branch.Name
+		}
+
+		items = append(items, cli.PickItem{
+			Label:   branch.Name,
+			Kind:    "remote branch",
+			Preview: commitPreview(repo, branch.ReferenceID),
+			Value:   value,
+		})
+	}
+
+	return items, nil
+}
+
+func freeBranches(branches []*gong.Branch) {
+	for _, branch := range branches {
+		gong.Free(branch)
+	}
+}
+
+// recentCommitCount is the number of recent commits offered by the picker.
+const recentCommitCount = 20
+
+// targetPickItems returns the branches, the remote branches, the releases, the
+// tags and the recent commits as pick items, skipping the current branch.
+func targetPickItems(repo *gong.Repository) ([]cli.PickItem, error) {
+	items, err := branchPickItems(repo, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := tagPickItems(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := commitPickItems(repo, recentCommitCount)
+	if err != nil {
+		return nil, err
+	}
+
+	items = append(items, tags...)
+
+	return append(items, commits...), nil
+}
+
+// tagPickItems returns the releases and the other tags as pick items.
+func tagPickItems(repo *gong.Repository) ([]cli.PickItem, error) {
+	tags, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []cli.PickItem
+
+	for _, tag := range tags {
+		kind := "tag"
+		if gong.NewRelease(tag) != nil {
+			kind = "release"
+		}
+
+		items = append(items, cli.PickItem{
+			Label:   tag.Name,
+			Kind:    kind,
+			Preview: commitPreview(repo, tag.CommitID),
+		})
+
+		gong.Free(tag)
+	}
+
+	return items, nil
+}
+
+// commitPickItems returns at most limit commits reachable from head as pick
+// items, newest first.
+func commitPickItems(repo *gong.Repository, limit int) ([]cli.PickItem, error) {
+	unborn, err := repo.Essence().IsHeadUnborn()
+	if err != nil || unborn {
+		return nil, err
+	}
+
+	walk, err := repo.Essence().Walk()
+	if err != nil {
+		return nil, err
+	}
+	defer gong.Free(walk)
+
+	walk.Sorting(lib.SortTime)
+
+	if err := walk.PushHead(); err != nil {
+		return nil, err
+	}
+
+	var items []cli.PickItem
+
+	err = walk.Iterate(func(commit *lib.Commit) bool {
+		items = append(items, cli.PickItem{
+			Label:   commit.Id().String()[:7],
+			Kind:    fmt.Sprintf("commit %s", commit.Summary()),
+			Preview: commitPreview(repo, commit.Id()),
+		})
+
+		return len(items) < limit
+	})
+
+	return items, err
+}
+
+// commitPreview describes the commit for the picker preview.
+func commitPreview(repo *gong.Repository, id *lib.Oid) string {
+	commit, err := repo.FindCommit(id)
+	if err != nil {
+		return ""
+	}
+	defer gong.Free(commit)
+
+	author := commit.Essence().Author()
+
+	return fmt.Sprintf(
+		"%s %s\n%s, %s",
+		id.String()[:7], commit.Essence().Summary(), author.Name, formatAge(author.When),
+	)
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/cli"
+	"github.com/erikjuhani/git-gong/gong"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		text    string
+		matches bool
+	}{
+		{
+			name:    "Should match an empty query to any text.",
+			query:   "",
+			text:    "main",
+			matches: true,
+		},
+		{
+			name:    "Should match characters in order with gaps.",
+			query:   "fbr",
+			text:    "feature/branch",
+			matches: true,
+		},
+		{
+			name:    "Should match ignoring case.",
+			query:   "MAIN",
+			text:    "main",
+			matches: true,
+		},
+		{
+			name:    "Should not match characters out of order.",
+			query:   "niam",
+			text:    "main",
+			matches: false,
+		},
+		{
+			name:    "Should not match a query longer than the text.",
+			query:   "mains",
+			text:    "main",
+			matches: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := cli.FuzzyMatch(tt.query, tt.text); ok != tt.matches {
+				t.Fatalf("FuzzyMatch(%q, %q) matched %t, expected %t", tt.query, tt.text, ok, tt.matches)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatchScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		better string
+		worse  string
+	}{
+		{
+			name:   "Should score a match at the start higher.",
+			query:  "fe",
+			better: "feature",
+			worse:  "safe-bet",
+		},
+		{
+			name:   "Should score a match after a separator higher.",
+			query:  "b",
+			better: "feature/bug",
+			worse:  "feature/xbug",
+		},
+		{
+			name:   "Should score consecutive matches higher.",
+			query:  "main",
+			better: "maint",
+			worse:  "mxaxixn",
+		},
+		{
+			name:   "Should score a shorter text higher on an otherwise equal match.",
+			query:  "main",
+			better: "main",
+			worse:  "mainline",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			better, _ := cli.FuzzyMatch(tt.query, tt.better)
+			worse, _ := cli.FuzzyMatch(tt.query, tt.worse)
+
+			if better <= worse {
+				t.Fatalf("%s scored %d, expected more than the %d of %s", tt.better, better, worse, tt.worse)
+			}
+		})
+	}
+}
+
+func TestTargetPickItems(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	first, err := repo.Seed("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateLocalBranch("feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Essence().References.Create("refs/remotes/origin/remote-branch", first.ID, false, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// A branch on more than one remote is picked with the remote.
+	for _, remoteName := range []string{"origin", "upstream"} {
+		if _, err := repo.Essence().References.Create(fmt.Sprintf("refs/remotes/%s/shared", remoteName), first.ID, false, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A remote branch with a local branch of the same name is not offered.
+	if _, err := repo.Essence().References.Create("refs/remotes/origin/feature", first.ID, false, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("v0.1.0", "first release"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("snapshot", "snapshot"); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := repo.Seed("second", "commit.me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := targetPickItems(repo.Repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []cli.PickItem{
+		{Label: "feature", Kind: "branch"},
+		{Label: "origin/remote-branch", Kind: "remote branch", Value: "remote-branch"},
+		{Label: "origin/shared", Kind: "remote branch", Value: "origin/shared"},
+		{Label: "upstream/shared", Kind: "remote branch", Value: "upstream/shared"},
+		{Label: "snapshot", Kind: "tag"},
+		{Label: "v0.1.0", Kind: "release"},
+		{Label: second.ID.String()[:7], Kind: "commit second"},
+		{Label: first.ID.String()[:7], Kind: "commit first"},
+	}
+
+	if len(items) != len(expected) {
+		t.Fatalf("got %d items %v, expected %d", len(items), items, len(expected))
+	}
+
+	for i, item := range items {
+		if item.Label != expected[i].Label || item.Kind != expected[i].Kind || item.Value != expected[i].Value {
+			t.Fatalf("item %d is %s (%s) %q, expected %s (%s) %q", i, item.Label, item.Kind, item.Value, expected[i].Label, expected[i].Kind, expected[i].Value)
+		}
+
+		if item.Preview == "" {
+			t.Fatalf("item %s has no preview", item.Label)
+		}
+	}
+}
+
+func TestCommitPickItemsLimit(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	items, err := commitPickItems(repo.Repository, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 0 {
+		t.Fatalf("unborn head offered %d commits", len(items))
+	}
+
+	for _, msg := range []string{"first", "second", "third"} {
+		if _, err := repo.Seed(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, err = commitPickItems(repo.Repository, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("got %d commits, expected 2", len(items))
+	}
+
+	if items[0].Kind != "commit third" {
+		t.Fatalf("newest commit is %s, expected third", items[0].Kind)
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.AddCommand(
+		pruneBranchesCmd,
+		pruneRestoreCmd,
+	)
+
+	pruneFlags()
+}
+
+var (
+	staleDays   int
+	pruneDryRun bool
+	pruneYes    bool
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune [subcommand]",
+	Short: "Prune stale branches.",
+	Long:  ``,
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var pruneBranchesCmd = &cobra.Command{
+	Use:   "branches",
+	Short: "Delete local branches that are merged or have gone stale.",
+	Long: `Find local branches that are fully merged into the default branch or have had
+  no commits in the given amount of days. The current branch, the default branch
+  and branches matching protected branch patterns are skipped.
+
+  Each branch is deleted after confirmation, use --yes to delete without asking
+  and --dry-run to only list the stale branches. Deleted branches are recorded and
+  can be recovered with gong prune restore <branchname>.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		defaultBranch, err := repo.DefaultBranchName()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		stale, err := repo.StaleBranches(staleDays)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		defer func() {
+			for _, branch := range stale {
+				gong.Free(branch)
+			}
+		}()
+
+		if len(stale) == 0 {
+			cmd.Println("no stale branches")
+			return
+		}
+
+		input := bufio.NewReader(cmd.InOrStdin())
+
+		for _, branch := range stale {
+			reason := branch.Reason(defaultBranch)
+
+			if pruneDryRun {
+				cmd.Printf("%s (%s)\n", branch.Name, reason)
+				continue
+			}
+
+			if !pruneYes {
+				cmd.Printf("delete branch %s (%s)? [y/N] ", branch.Name, reason)
+
+				answer, _ := input.ReadString('\n')
+				if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+					continue
+				}
+			}
+
+			if err := repo.PruneBranch(branch.Branch); err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			cmd.Printf("deleted branch %s (%s)\n", branch.Name, reason)
+		}
+	},
+}
+
+var pruneRestoreCmd = &cobra.Command{
+	Use:   "restore [branchname]",
+	Short: "Restore a pruned branch.",
+	Long: `Restore a branch deleted by gong prune branches to its tip at the time of pruning,
+  along with its upstream, description and auto-stash. Without a branchname the
+  restorable branches are listed.
+
+  Every prune is recorded separately. The branchname restores the latest record,
+  an earlier record of a branch pruned more than once is restored with
+  <branchname>@<n>.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		if len(args) == 0 {
+			pruned, err := repo.PrunedBranches()
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			for _, branchName := range pruned {
+				cmd.Println(branchName)
+
+				records, err := repo.PruneRecords(branchName)
+				if err != nil {
+					cmd.PrintErr(err)
+					return
+				}
+
+				if len(records) < 2 {
+					continue
+				}
+
+				for _, record := range records {
+					cmd.Printf("  %s\n", record)
+				}
+			}
+			return
+		}
+
+		branch, err := repo.RestoreBranch(args[0])
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(branch)
+
+		cmd.Printf("restored branch %s\n", branch.Name)
+	},
+}
+
+func pruneFlags() {
+	pruneBranchesCmd.Flags().IntVar(
+		&staleDays, "days", 90,
+		"Consider branches without commits in the given amount of days stale, 0 only prunes merged branches",
+	)
+	pruneBranchesCmd.Flags().BoolVar(
+		&pruneDryRun, "dry-run", false,
+		"List stale branches without deleting them",
+	)
+	pruneBranchesCmd.Flags().BoolVarP(
+		&pruneYes, "yes", "y", false,
+		"Delete stale branches without confirmation",
+	)
+}
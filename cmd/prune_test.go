@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/gong"
+	lib "github.com/libgit2/git2go/v31"
+)
+
+func TestPruneBranchesCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		deleted bool
+	}{
+		{
+			name: `Command gong prune branches --dry-run.
+				Should only list the stale branches.`,
+			args:    []string{"--dry-run"},
+			deleted: false,
+		},
+		{
+			name: `Command gong prune branches --yes.
+				Should delete branches merged into the default branch and record them.`,
+			args:    []string{"--dry-run=false", "--yes"},
+			deleted: true,
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	_, err = repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.CreateLocalBranch("gong-merged")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workdir := repo.Path
+
+	if err := os.Chdir(workdir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{pruneCmd.Name(), pruneBranchesCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			// Eat up the output
+			rootCmd.SetOut(bytes.NewBuffer(nil))
+
+			err = rootCmd.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = repo.FindBranch("gong-merged", lib.BranchLocal)
+			if deleted := err != nil; deleted != tt.deleted {
+				t.Fatal(fmt.Errorf("branch gong-merged deleted %t, expected %t", deleted, tt.deleted))
+			}
+
+			if !tt.deleted {
+				return
+			}
+
+			pruned, err := repo.PrunedBranches()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(pruned) != 1 || pruned[0] != "gong-merged" {
+				t.Fatal(fmt.Errorf("pruned branches %v do not contain gong-merged", pruned))
+			}
+		})
+	}
+
+	pruneYes = false
+}
+
+func TestPruneRestoreCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	if _, err := repo.Seed(commitMsg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { pruneYes = false }()
+
+	run := func(args ...string) {
+		rootCmd.SetArgs(append([]string{pruneCmd.Name()}, args...))
+		rootCmd.SetOut(bytes.NewBuffer(nil))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prune := func(owner string) {
+		branch, err := repo.CreateLocalBranch("gong-merged")
+		if err != nil {
+			t.Fatal(err)
+		}
+		gong.Free(branch)
+
+		if err := repo.SetBranchMeta("gong-merged", &gong.BranchMeta{Owner: owner}); err != nil {
+			t.Fatal(err)
+		}
+
+		run(pruneBranchesCmd.Name(), "--dry-run=false", "--yes")
+	}
+
+	t.Run(`Command gong prune branches on a re-created branch.
+		Should keep a record of every prune.`, func(t *testing.T) {
+		prune("first")
+		prune("second")
+
+		records, err := repo.PruneRecords("gong-merged")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 2 || records[0] != "gong-merged@1" || records[1] != "gong-merged@2" {
+			t.Fatalf("expected two prune records, got %v", records)
+		}
+
+		meta, err := repo.BranchMeta("gong-merged")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !meta.Empty() {
+			t.Fatalf("expected the metadata to be removed with the branch, got %v", meta)
+		}
+	})
+
+	t.Run(`Command gong prune restore <branchname>@<n>.
+		Should restore the branch and its metadata from the record.`, func(t *testing.T) {
+		run(pruneRestoreCmd.Name(), "gong-merged@1")
+
+		branch, err := repo.FindBranch("gong-merged", lib.BranchLocal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gong.Free(branch)
+
+		meta, err := repo.BranchMeta("gong-merged")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if meta.Owner != "first" {
+			t.Fatalf("expected the owner of the first record, got %q", meta.Owner)
+		}
+
+		records, err := repo.PruneRecords("gong-merged")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 1 || records[0] != "gong-merged@2" {
+			t.Fatalf("expected the second record to be kept, got %v", records)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(rebaseCmd)
+
+	rebaseFlags()
+}
+
+var (
+	rebaseContinue bool
+	rebaseSkip     bool
+	rebaseAbort    bool
+)
+
+var rebaseCmd = &cobra.Command{
+	Use:   "rebase [onto]",
+	Short: "Replays the commits of the current branch onto another revision.",
+	Long: `Replays the commits of the current branch that are not on onto on top of
+  onto. Onto can be any revision e.g. a branch, a tag or a commit, and defaults
+  to the upstream of the branch or the default branch.
+
+  The commits are replayed in memory. If a commit conflicts, the rebase stops
+  and leaves the conflict markers in the working tree. Resolve the conflicts and
+  carry on with gong rebase --continue, drop the commit with gong rebase --skip,
+  or return to the branch as it was with gong rebase --abort.
+
+  Uncommitted changes are stashed for the rebase and restored afterwards.
+  Protected branches and commits already on a protected branch are never
+  rewritten.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if rebaseContinue || rebaseSkip || rebaseAbort {
+			return cobra.NoArgs(cmd, args)
+		}
+
+		return cobra.MaximumNArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		if rebaseAbort {
+			branch, err := repo.AbortRebase()
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+			defer gong.Free(branch)
+
+			cmd.Printf("rebase aborted, checkout to branch %s\n", branch.Name)
+			return
+		}
+
+		if rebaseContinue || rebaseSkip {
+			resume := repo.ContinueRebase
+			if rebaseSkip {
+				resume = repo.SkipRebase
+			}
+
+			branch, err := resume()
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+			defer gong.Free(branch)
+
+			cmd.Printf("rebased branch %s\n", branch.Name)
+			return
+		}
+
+		onto := ""
+		if len(args) > 0 {
+			onto = args[0]
+		}
+
+		revision, err := repo.Rebase(onto)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(revision)
+
+		branch, err := repo.CurrentBranch()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(branch)
+
+		cmd.Printf("rebased branch %s onto %s\n", branch.Name, revision.Name)
+	},
+}
+
+func rebaseFlags() {
+	rebaseCmd.Flags().BoolVar(
+		&rebaseContinue, "continue", false,
+		"Commit the resolved conflicts of a stopped rebase and replay the rest of the commits",
+	)
+	rebaseCmd.Flags().BoolVar(
+		&rebaseSkip, "skip", false,
+		"Drop the conflicting commit of a stopped rebase and replay the rest of the commits",
+	)
+	rebaseCmd.Flags().BoolVar(
+		&rebaseAbort, "abort", false,
+		"Abort a stopped rebase and return to the branch as it was before the rebase",
+	)
+}
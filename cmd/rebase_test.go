@@ -0,0 +1,415 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/config"
+	"github.com/erikjuhani/git-gong/gong"
+)
+
+func TestRebaseCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	if _, err := repo.Seed("default-commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := repo.Seed("gong-branch-commit", "a.file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	onto, err := repo.Seed("main-commit", "b.file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Uncommitted work is stashed for the rebase and restored afterwards.
+	if err := ioutil.WriteFile(filepath.Join(repo.Path, "c.file"), []byte("temp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd.SetArgs([]string{rebaseCmd.Name(), "main"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	branch, err := repo.Head.Branch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if branch.Name != "gong-branch" {
+		t.Fatalf("expected to stay on branch gong-branch, got %s", branch.Name)
+	}
+
+	head, err := repo.Head.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if head.ID.String() == original.ID.String() {
+		t.Fatal("expected the commit to be replayed")
+	}
+
+	if head.Parent().ID.String() != onto.ID.String() {
+		t.Fatalf("expected the commit to be replayed onto %s, got %s", onto.ID.String(), head.Parent().ID.String())
+	}
+
+	for _, file := range []string{"a.file", "b.file", "c.file"} {
+		if _, err := os.Stat(filepath.Join(repo.Path, file)); err != nil {
+			t.Fatalf("expected %s to exist after the rebase", file)
+		}
+	}
+}
+
+func TestRebaseConflictCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	commitFile := func(content string) *gong.Commit {
+		if err := ioutil.WriteFile(filepath.Join(repo.Path, "a.file"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		tree, err := repo.AddToIndex([]string{"a.file"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		commit, err := repo.CreateCommit(tree, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return commit
+	}
+
+	if _, err := repo.Seed("default-commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	original := commitFile("gong-branch\n")
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	onto := commitFile("main\n")
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	rebase := func(args ...string) {
+		rootCmd.SetArgs(append([]string{rebaseCmd.Name()}, args...))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func() {
+		rebaseContinue = false
+		rebaseAbort = false
+	}()
+
+	t.Run("Command gong rebase <onto> with conflicts. Should stop with the conflicts in place", func(t *testing.T) {
+		rebase("main")
+
+		inProgress, err := repo.RebaseInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !inProgress {
+			t.Fatal("expected rebase to be in progress")
+		}
+
+		conflicts, err := repo.Conflicts()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(conflicts) != 1 || conflicts[0] != "a.file" {
+			t.Fatalf("expected a.file to be conflicted, got %v", conflicts)
+		}
+	})
+
+	t.Run("Command gong rebase --abort. Should return to the branch as it was", func(t *testing.T) {
+		rebase("--abort")
+		rebaseAbort = false
+
+		head, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if head.ID.String() != original.ID.String() {
+			t.Fatalf("expected head to be %s, got %s", original.ID.String(), head.ID.String())
+		}
+
+		branch, err := repo.Head.Branch()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if branch.Name != "gong-branch" {
+			t.Fatalf("expected to be on branch gong-branch, got %s", branch.Name)
+		}
+	})
+
+	t.Run("Command gong rebase --continue. Should commit the resolution and finish the rebase", func(t *testing.T) {
+		rebase("main")
+
+		if err := ioutil.WriteFile(filepath.Join(repo.Path, "a.file"), []byte("resolved\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		rebase("--continue")
+		rebaseContinue = false
+
+		inProgress, err := repo.RebaseInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if inProgress {
+			t.Fatal("expected rebase to be finished")
+		}
+
+		branch, err := repo.Head.Branch()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if branch.Name != "gong-branch" {
+			t.Fatalf("expected to be on branch gong-branch, got %s", branch.Name)
+		}
+
+		head, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if head.Parent().ID.String() != onto.ID.String() {
+			t.Fatalf("expected the commit to be replayed onto %s, got %s", onto.ID.String(), head.Parent().ID.String())
+		}
+
+		if head.Message != "gong-branch\n" {
+			t.Fatalf("expected the message of the replayed commit to be kept, got %q", head.Message)
+		}
+	})
+}
+
+func TestRebaseSkipCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	commitFile := func(content string) *gong.Commit {
+		if err := ioutil.WriteFile(filepath.Join(repo.Path, "a.file"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		tree, err := repo.AddToIndex([]string{"a.file"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		commit, err := repo.CreateCommit(tree, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return commit
+	}
+
+	if _, err := repo.Seed("default-commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile("gong-branch\n")
+
+	if _, err := repo.Seed("b-commit", "b.file"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	onto := commitFile("main\n")
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	rebase := func(args ...string) {
+		rootCmd.SetArgs(append([]string{rebaseCmd.Name()}, args...))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func() { rebaseSkip = false }()
+
+	t.Run("Command gong rebase --skip. Should drop the conflicting commit and replay the rest", func(t *testing.T) {
+		rebase("main")
+
+		rebase("--skip")
+		rebaseSkip = false
+
+		inProgress, err := repo.RebaseInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if inProgress {
+			t.Fatal("expected rebase to be finished")
+		}
+
+		head, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(head)
+
+		if head.Parent().ID.String() != onto.ID.String() {
+			t.Fatalf("expected the rest of the commits to be replayed onto %s, got %s", onto.ID.String(), head.Parent().ID.String())
+		}
+
+		if head.Message != "b-commit" {
+			t.Fatalf("expected the replayed commit to be b-commit, got %q", head.Message)
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(repo.Path, "a.file"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(content) != "main\n" {
+			t.Fatalf("expected the skipped changes to be dropped, got %q", content)
+		}
+
+		if _, err := os.Stat(filepath.Join(repo.Path, "b.file")); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestRebaseProtectedRemoteBranchCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	if _, err := repo.Seed("default-commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	shared, err := repo.Seed("shared-commit", "a.file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The commit is on a protected branch of the remote only.
+	if _, err := repo.Essence().References.Create("refs/remotes/origin/release/1", shared.ID, false, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Seed("main-commit", "b.file"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	config.ProtectedBranchPatterns.AddPattern("release/*")
+	defer func() { *config.ProtectedBranchPatterns = config.Patterns{} }()
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	errOut := bytes.NewBuffer(nil)
+	rootCmd.SetErr(errOut)
+	defer rootCmd.SetErr(nil)
+
+	rootCmd.SetArgs([]string{rebaseCmd.Name(), "main"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := repo.Head.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if head.ID.String() != shared.ID.String() {
+		t.Fatal("expected the commit on the protected remote branch not to be rebased")
+	}
+
+	if !strings.Contains(errOut.String(), "origin/release/1") {
+		t.Fatalf("expected the protected remote branch to be reported, got %q", errOut.String())
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+
+	removeCmd.AddCommand(
+		removeWorktreeCmd,
+	)
+
+	removeFlags()
+}
+
+var removeWorktreeForce bool
+
+var removeCmd = &cobra.Command{
+	Use:   "remove [subcommand]",
+	Short: "Remove worktrees.",
+	Long:  ``,
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var removeWorktreeCmd = &cobra.Command{
+	Use:   "worktree [branchname|path]",
+	Short: "Remove a linked worktree.",
+	Long: `Remove the linked worktree of the branch, or the linked worktree at path.
+  The branch itself is kept. Worktrees with uncommitted changes are only
+  removed with --force. The main and the current worktree cannot be removed.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		worktree, err := repo.RemoveWorktree(args[0], removeWorktreeForce)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Printf("removed worktree %s\n", worktree.Path)
+	},
+}
+
+func removeFlags() {
+	removeWorktreeCmd.Flags().BoolVarP(
+		&removeWorktreeForce, "force", "f", false,
+		"Remove the worktree even if it has uncommitted changes",
+	)
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/gong"
+	lib "github.com/libgit2/git2go/v31"
+)
+
+func TestSwitchAndRemoveWorktreeCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		worktree bool
+	}{
+		{
+			name: `Command gong switch branch <branchname> --worktree <path>.
+				Should check out the branch to a linked worktree at <path> and
+				leave the current checkout untouched.`,
+			args:     []string{switchCmd.Name(), switchBranchCmd.Name(), "gong-branch", "--worktree"},
+			worktree: true,
+		},
+		{
+			name: `Command gong remove worktree <branchname>.
+				Should remove the linked worktree of the branch.`,
+			args:     []string{removeCmd.Name(), removeWorktreeCmd.Name(), "gong-branch"},
+			worktree: false,
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	_, err = repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "gong-worktree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "gong-branch")
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { switchWorktree = false }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.worktree {
+				args = append(args, path)
+			}
+
+			rootCmd.SetArgs(args)
+
+			// Eat up the output
+			rootCmd.SetOut(bytes.NewBuffer(nil))
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			worktree, err := repo.FindWorktree("gong-branch")
+			if found := err == nil; found != tt.worktree {
+				t.Fatal(fmt.Errorf("worktree of gong-branch found %t, expected %t", found, tt.worktree))
+			}
+
+			if tt.worktree {
+				evaluated, _ := filepath.EvalSymlinks(path)
+				actual, _ := filepath.EvalSymlinks(worktree.Path)
+
+				if actual != evaluated {
+					t.Fatal(fmt.Errorf("worktree path %s does not equal to expected path %s", worktree.Path, path))
+				}
+			}
+
+			branch, err := repo.CurrentBranch()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if branch.Name != "main" {
+				t.Fatal(fmt.Errorf("current branch %s changed, expected main", branch.Name))
+			}
+		})
+	}
+}
+
+func TestWorktreeInfoAndStashCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	_, err = repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "gong-worktree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "gong-branch")
+
+	if _, _, err := repo.SwitchWorktree("gong-branch", path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(path); err != nil {
+		t.Fatal(err)
+	}
+
+	worktreeRepo, err := gong.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gong.Free(worktreeRepo)
+
+	if !worktreeRepo.IsLinkedWorktree() {
+		t.Fatal("repository opened from a linked worktree is not a linked worktree")
+	}
+
+	t.Run(`Command gong info.
+		Should show the branch and the path of the linked worktree.`, func(t *testing.T) {
+		outBuff := bytes.NewBuffer(nil)
+
+		rootCmd.SetArgs([]string{infoCmd.Name()})
+		rootCmd.SetOut(outBuff)
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+
+		out := outBuff.String()
+
+		if !strings.Contains(out, "Branch gong-branch\n") {
+			t.Fatalf("info %q does not show the branch of the worktree", out)
+		}
+
+		if !strings.Contains(out, "Worktree ") || !strings.Contains(out, filepath.Base(path)+"\n") {
+			t.Fatalf("info %q does not show the path of the worktree", out)
+		}
+	})
+
+	stashed := filepath.Join(path, "stash.me")
+	if err := ioutil.WriteFile(stashed, []byte("---i-am-untracked-and-i-shall-be-stashed---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run(`Command gong switch branch <branchname> in a linked worktree.
+		Should stash the changes of the worktree for its branch and leave the
+		main worktree untouched.`, func(t *testing.T) {
+		rootCmd.SetArgs([]string{switchCmd.Name(), switchBranchCmd.Name(), "worktree-branch"})
+		rootCmd.SetOut(bytes.NewBuffer(nil))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(stashed); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be stashed", stashed)
+		}
+
+		branch, err := repo.FindBranch("gong-branch", lib.BranchLocal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(branch)
+
+		if !gong.NewStashCollection(repo.Essence()).Has(branch) {
+			t.Fatal("stash of the worktree is not bound to gong-branch")
+		}
+
+		mainBranch, err := repo.CurrentBranch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(mainBranch)
+
+		if mainBranch.Name != "main" {
+			t.Fatalf("main worktree switched to %s", mainBranch.Name)
+		}
+	})
+
+	t.Run(`Command gong switch branch <branchname> in a linked worktree.
+		Should pop the stash of the branch back into the worktree.`, func(t *testing.T) {
+		rootCmd.SetArgs([]string{switchCmd.Name(), switchBranchCmd.Name(), "gong-branch"})
+		rootCmd.SetOut(bytes.NewBuffer(nil))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(stashed); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(filepath.Join(repo.Path, "stash.me")); !os.IsNotExist(err) {
+			t.Fatal("stash of the worktree was popped into the main worktree")
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+
+	renameCmd.AddCommand(
+		renameBranchCmd,
+	)
+
+	renameFlags()
+}
+
+var migrateDefaultBranch bool
+
+var renameCmd = &cobra.Command{
+	Use:   "rename [subcommand]",
+	Short: "Rename branches.",
+	Long:  ``,
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var renameBranchCmd = &cobra.Command{
+	Use:   "branch [oldname] [newname]",
+	Short: "Rename branch oldname to newname.",
+	Long: `Rename a local branch. The reflog, upstream and gong stashes of the branch
+  are carried over to the new name. Renaming the current or the default branch is
+  allowed.
+
+  To migrate the default branch from master to main use the --migrate flag.
+  When no branch names are given, master is renamed to main.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if migrateDefaultBranch && len(args) == 0 {
+			return nil
+		}
+
+		if len(args) != 2 {
+			return errors.New("requires an old and a new branch name")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		if migrateDefaultBranch {
+			from, to := "master", gong.DefaultReference
+
+			if len(args) == 2 {
+				from, to = args[0], args[1]
+			}
+
+			branch, err := repo.MigrateDefaultBranch(from, to)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+			defer gong.Free(branch)
+
+			cmd.Printf("migrated default branch %s to %s\n", from, branch.Name)
+			return
+		}
+
+		branch, err := repo.RenameBranch(args[0], args[1])
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(branch)
+
+		cmd.Printf("renamed branch %s to %s\n", args[0], branch.Name)
+	},
+}
+
+func renameFlags() {
+	renameBranchCmd.Flags().BoolVar(
+		&migrateDefaultBranch, "migrate", false,
+		"Rename the branch and set it as the default branch, by default master to main.",
+	)
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/gong"
+	lib "github.com/libgit2/git2go/v31"
+)
+
+func TestRenameBranchCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		migrate  bool
+	}{
+		{
+			name: `Command gong rename branch <oldname> <newname>.
+				Should rename the branch <oldname> to <newname>.`,
+			args:     []string{"gong-branch", "gong-renamed"},
+			expected: "gong-renamed",
+		},
+		{
+			name: `Command gong rename branch <oldname> <newname>.
+				Should rename the current branch and move HEAD along.`,
+			args:     []string{"main", "trunk"},
+			expected: "trunk",
+		},
+		{
+			name: `Command gong rename branch --migrate.
+				Should rename the branch master to main and set it as the default branch.`,
+			args:     []string{"--migrate"},
+			expected: "main",
+			migrate:  true,
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	_, err = repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.CreateLocalBranch("gong-branch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workdir := repo.Path
+
+	if err := os.Chdir(workdir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.migrate {
+				if _, err := repo.RenameBranch("trunk", "master"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			args := []string{renameCmd.Name(), renameBranchCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			// Eat up the output
+			rootCmd.SetOut(bytes.NewBuffer(nil))
+
+			err = rootCmd.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = repo.FindBranch(tt.expected, lib.BranchLocal)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.migrate {
+				if _, err := repo.FindBranch(tt.args[0], lib.BranchLocal); err == nil {
+					t.Fatal(fmt.Errorf("branch %s still exists after rename", tt.args[0]))
+				}
+				return
+			}
+
+			defaultBranch, err := repo.DefaultBranchName()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if defaultBranch != tt.expected {
+				t.Fatal(fmt.Errorf("default branch %s does not equal to expected %s", defaultBranch, tt.expected))
+			}
+		})
+	}
+
+	migrateDefaultBranch = false
+}
+
+func TestRenameBranchPreviousCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	if _, err := repo.Seed(commitMsg); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run(`Command gong switch - after gong rename branch <previous> <newname>.
+		Should switch to the renamed previous branch.`, func(t *testing.T) {
+		for _, args := range [][]string{
+			{renameCmd.Name(), renameBranchCmd.Name(), "gong-branch", "gong-renamed"},
+			{switchCmd.Name(), "-"},
+		} {
+			rootCmd.SetArgs(args)
+			rootCmd.SetOut(bytes.NewBuffer(nil))
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		current, err := repo.CurrentBranch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(current)
+
+		if current.Name != "gong-renamed" {
+			t.Fatalf("expected to switch to gong-renamed, got %s", current.Name)
+		}
+	})
+}
+
+func TestRenameDefaultBranchCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	if _, err := repo.Seed(commitMsg); err != nil {
+		t.Fatal(err)
+	}
+
+	// init.defaultBranch only names the branches of new repositories and
+	// must not be taken as the default branch of this one.
+	cfg, err := repo.Essence().Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cfg.Free()
+
+	if err := cfg.SetString("init.defaultBranch", "master"); err != nil {
+		t.Fatal(err)
+	}
+
+	defaultBranch, err := repo.DefaultBranchName()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if defaultBranch != "main" {
+		t.Fatalf("expected default branch main, got %s", defaultBranch)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd.SetArgs([]string{renameCmd.Name(), renameBranchCmd.Name(), "main", "trunk"})
+	rootCmd.SetOut(bytes.NewBuffer(nil))
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if defaultBranch, err = repo.DefaultBranchName(); err != nil {
+		t.Fatal(err)
+	}
+
+	if defaultBranch != "trunk" {
+		t.Fatalf("expected the default branch to be renamed to trunk, got %s", defaultBranch)
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(revertCmd)
+
+	revertFlags()
+}
+
+var (
+	revertContinue bool
+	revertAbort    bool
+	revertMainline uint
+)
+
+var revertCmd = &cobra.Command{
+	Use:   "revert [revision...]",
+	Short: "Creates commits undoing the changes of commits.",
+	Long: `Creates a commit undoing the changes of each commit, in the given order. The
+  message of the commit refers to the reverted commit. Unlike gong undo the
+  history is not rewritten, so commits that are already shared can be reverted.
+
+  Merge commits are reverted relative to the mainline parent selected with
+  --mainline.
+
+  If a revert conflicts, it stops and leaves the conflict markers in the working
+  tree. Resolve the conflicts and carry on with gong revert --continue, or
+  return to the state before the revert with gong revert --abort.
+
+  Reverting creates commits, so like committing it is refused on a protected
+  branch. Revert on another branch and merge it into the protected branch
+  instead.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if revertContinue || revertAbort {
+			return cobra.NoArgs(cmd, args)
+		}
+
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		if revertAbort {
+			if err := repo.AbortRevert(); err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			cmd.Println("revert aborted")
+			return
+		}
+
+		if revertContinue {
+			if err := repo.ContinueRevert(); err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			cmd.Println("revert finished")
+			return
+		}
+
+		reverted, err := repo.Revert(args, revertMainline)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Printf("reverted %d commits\n", reverted)
+	},
+}
+
+func revertFlags() {
+	revertCmd.Flags().BoolVar(
+		&revertContinue, "continue", false,
+		"Commit the resolved conflicts of a stopped revert and revert the rest of the commits",
+	)
+	revertCmd.Flags().BoolVar(
+		&revertAbort, "abort", false,
+		"Abort a stopped revert and return to the state before the revert",
+	)
+	revertCmd.Flags().UintVarP(
+		&revertMainline, "mainline", "m", 0,
+		"The parent number, starting from 1, merge commits are reverted relative to",
+	)
+}
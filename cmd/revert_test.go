@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/config"
+	"github.com/erikjuhani/git-gong/gong"
+)
+
+func TestRevertCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		merge    bool
+		reverted []string
+		kept     []string
+		commits  int
+	}{
+		{
+			name:     "Command gong revert <revision>. Should create a commit undoing the changes of the commit",
+			args:     []string{"HEAD~1"},
+			reverted: []string{"b.file"},
+			kept:     []string{"a.file", "c.file"},
+			commits:  1,
+		},
+		{
+			name:     "Command gong revert <revision> <revision>. Should revert the commits in order",
+			args:     []string{"HEAD", "HEAD~1"},
+			reverted: []string{"b.file", "c.file"},
+			kept:     []string{"a.file"},
+			commits:  2,
+		},
+		{
+			name:     "Command gong revert -m 1 <merge>. Should undo the changes merged to the mainline",
+			args:     []string{"-m", "1"},
+			merge:    true,
+			reverted: []string{"d.file"},
+			kept:     []string{"a.file", "b.file", "c.file"},
+			commits:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { revertMainline = 0 }()
+
+			repo, clean, err := gong.TestRepo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clean()
+
+			for _, file := range []string{"a.file", "b.file", "c.file"} {
+				if _, err := repo.Seed(file, file); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.Seed("d.file", "d.file"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.CheckoutBranch("main"); err != nil {
+				t.Fatal(err)
+			}
+
+			before, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			args := tt.args
+
+			if tt.merge {
+				if err := repo.Merge("gong-branch", gong.MergeNoFastForward); err != nil {
+					t.Fatal(err)
+				}
+
+				if before, err = repo.Head.Commit(); err != nil {
+					t.Fatal(err)
+				}
+
+				args = append(args, before.ID.String())
+			}
+
+			if err := os.Chdir(repo.Path); err != nil {
+				t.Fatal(err)
+			}
+
+			rootCmd.SetArgs(append([]string{revertCmd.Name()}, args...))
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			head, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			commit := head
+			for i := 0; i < tt.commits; i++ {
+				if !strings.HasPrefix(commit.Message, "Revert \"") {
+					t.Fatal(fmt.Errorf("expected a revert commit, got message %q", commit.Message))
+				}
+				commit = commit.Parent()
+			}
+
+			if commit.ID.String() != before.ID.String() {
+				t.Fatalf("expected %d revert commits on top of %s", tt.commits, before.ID.String())
+			}
+
+			for _, file := range tt.reverted {
+				if _, err := os.Stat(filepath.Join(repo.Path, file)); err == nil {
+					t.Fatalf("expected %s to be reverted", file)
+				}
+			}
+
+			for _, file := range tt.kept {
+				if _, err := os.Stat(filepath.Join(repo.Path, file)); err != nil {
+					t.Fatalf("expected %s to be kept", file)
+				}
+			}
+		})
+	}
+}
+
+func TestRevertConflictCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	commitFile := func(content string) *gong.Commit {
+		if err := ioutil.WriteFile(filepath.Join(repo.Path, "a.file"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		tree, err := repo.AddToIndex([]string{"a.file"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		commit, err := repo.CreateCommit(tree, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return commit
+	}
+
+	if _, err := repo.Seed("default-commit", "a.file"); err != nil {
+		t.Fatal(err)
+	}
+
+	reverted := commitFile("one\n")
+	before := commitFile("two\n")
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	revert := func(args ...string) {
+		rootCmd.SetArgs(append([]string{revertCmd.Name()}, args...))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func() {
+		revertContinue = false
+		revertAbort = false
+	}()
+
+	t.Run("Command gong revert <revision> with conflicts. Should stop with the conflicts in place", func(t *testing.T) {
+		revert(reverted.ID.String())
+
+		inProgress, err := repo.RevertInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !inProgress {
+			t.Fatal("expected revert to be in progress")
+		}
+
+		conflicts, err := repo.Conflicts()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(conflicts) != 1 || conflicts[0] != "a.file" {
+			t.Fatalf("expected a.file to be conflicted, got %v", conflicts)
+		}
+	})
+
+	t.Run("Command gong revert --abort. Should return to the state before the revert", func(t *testing.T) {
+		revert("--abort")
+		revertAbort = false
+
+		inProgress, err := repo.RevertInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if inProgress {
+			t.Fatal("expected revert to be aborted")
+		}
+
+		head, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(head)
+
+		if !head.ID.Equal(before.ID) {
+			t.Fatalf("expected head to be %s, got %s", before.ID.String(), head.ID.String())
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(repo.Path, "a.file"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(content) != "two\n" {
+			t.Fatalf("expected a.file to be restored, got %q", content)
+		}
+	})
+
+	t.Run("Command gong revert --continue. Should commit the resolution and finish the revert", func(t *testing.T) {
+		revert(reverted.ID.String())
+
+		if err := ioutil.WriteFile(filepath.Join(repo.Path, "a.file"), []byte("resolved\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		revert("--continue")
+		revertContinue = false
+
+		inProgress, err := repo.RevertInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if inProgress {
+			t.Fatal("expected revert to be finished")
+		}
+
+		branch, err := repo.Head.Branch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(branch)
+
+		if branch.Name != "main" {
+			t.Fatalf("expected to be on branch main, got %s", branch.Name)
+		}
+
+		head, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(head)
+
+		if head.Parent().ID.String() != before.ID.String() {
+			t.Fatalf("expected the revert commit on top of %s, got %s", before.ID.String(), head.Parent().ID.String())
+		}
+
+		if !strings.HasPrefix(head.Message, "Revert \"") {
+			t.Fatalf("expected a revert commit, got message %q", head.Message)
+		}
+	})
+}
+
+func TestRevertProtectedBranchCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	for _, file := range []string{"a.file", "b.file"} {
+		if _, err := repo.Seed(file, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before, err := repo.Head.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gong.Free(before)
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	config.ProtectedBranchPatterns.AddPattern("main")
+	defer func() { *config.ProtectedBranchPatterns = config.Patterns{} }()
+
+	t.Run("Command gong revert <revision> on a protected branch. Should refuse to revert", func(t *testing.T) {
+		rootCmd.SetArgs([]string{revertCmd.Name(), "HEAD"})
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+
+		head, err := repo.Head.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(head)
+
+		if !head.ID.Equal(before.ID) {
+			t.Fatalf("expected head to stay at %s, got %s", before.ID.String(), head.ID.String())
+		}
+
+		if _, err := os.Stat(filepath.Join(repo.Path, "b.file")); err != nil {
+			t.Fatal("expected b.file to be kept")
+		}
+	})
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(stashCmd)
+
+	stashCmd.AddCommand(
+		stashListCmd,
+		stashShowCmd,
+		stashApplyCmd,
+		stashPopCmd,
+		stashDropCmd,
+		stashSaveCmd,
+		stashBranchCmd,
+	)
+}
+
+var stashCmd = &cobra.Command{
+	Use:   "stash [subcommand]",
+	Short: "List and manage auto-stashes and named stashes.",
+	Long: `Gong stashes uncommitted changes automatically when switching branches. The
+  auto-stash is bound to the branch it was created on and popped when switching
+  back to the branch.
+
+  Stashes are referred by the branch name of the auto-stash or the name of a
+  named stash. If no name is given the stash of the current branch is used.`,
+	Args: cobra.MinimumNArgs(1),
+}
+
+var stashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stashes with the branch they belong to and their age.",
+	Long:  ``,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		stashes, err := repo.Stashes.List()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		dangling := repo.Stashes.Dangling()
+
+		if len(stashes) == 0 && len(dangling) == 0 {
+			cmd.Println("no stashes")
+			return
+		}
+
+		for _, stash := range stashes {
+			cmd.Printf("%s %s (%s)\n", stashKind(stash), stash.Name, formatAge(stash.Created))
+		}
+
+		for _, stash := range dangling {
+			cmd.Printf("%s %s (dropped outside gong, remove with gong stash drop %s)\n", stashKind(stash), stash.Name, stash.Name)
+		}
+	},
+}
+
+var stashShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show the changes recorded in the stash as a diff.",
+	Long:  ``,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		stash, err := lookupStash(repo, args)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		diff, err := repo.Stashes.Diff(stash)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Print(diff)
+	},
+}
+
+var stashApplyCmd = &cobra.Command{
+	Use:   "apply [name]",
+	Short: "Apply the stash and keep it.",
+	Long:  ``,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		stash, err := lookupStash(repo, args)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		if err := repo.Stashes.Apply(stash); err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Printf("applied stash %s\n", stash.Name)
+	},
+}
+
+var stashPopCmd = &cobra.Command{
+	Use:   "pop [name]",
+	Short: "Apply the stash and drop it.",
+	Long:  ``,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		stash, err := lookupStash(repo, args)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		if err := repo.Stashes.PopStash(stash); err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Printf("popped stash %s\n", stash.Name)
+	},
+}
+
+var stashDropCmd = &cobra.Command{
+	Use:   "drop [name]",
+	Short: "Drop the stash without applying it.",
+	Long:  ``,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		// A dangling stash is returned with an error, dropping it removes the
+		// binding.
+		stash, err := lookupStash(repo, args)
+		if stash == nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		if err := repo.Stashes.Drop(stash); err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Printf("dropped stash %s\n", stash.Name)
+	},
+}
+
+var stashSaveCmd = &cobra.Command{
+	Use:   "save [name]",
+	Short: "Stash uncommitted changes with a name.",
+	Long: `Stash uncommitted changes including untracked files with the given name.
+  Named stashes are not popped automatically when switching branches.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		stash, err := repo.Stashes.Save(args[0])
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		cmd.Printf("saved stash %s\n", stash.Name)
+	},
+}
+
+var stashBranchCmd = &cobra.Command{
+	Use:   "branch [branchname] [name]",
+	Short: "Turn a stash into a new branch.",
+	Long: `Create a new branch with branchname from the commit the stash was created on,
+  switch to the branch and pop the stash onto it.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		stash, err := lookupStash(repo, args[1:])
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		branch, err := repo.StashBranch(args[0], stash)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(branch)
+
+		cmd.Printf("created branch %s from stash %s\n", branch.Name, stash.Name)
+	},
+}
+
+// lookupStash returns the stash named by the first argument or the stash of
+// the current branch when no arguments were given.
+func lookupStash(repo *gong.Repository, args []string) (*gong.Stash, error) {
+	if len(args) > 0 {
+		return repo.Stashes.Lookup(args[0])
+	}
+
+	branch, err := repo.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	defer gong.Free(branch)
+
+	return repo.Stashes.Lookup(branch.Name)
+}
+
+func stashKind(stash *gong.Stash) string {
+	if stash.IsAuto() {
+		return "branch"
+	}
+
+	return "named"
+}
+
+func formatAge(t time.Time) string {
+	age := time.Since(t)
+
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%d minutes ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%d days ago", int(age.Hours()/24))
+	}
+}
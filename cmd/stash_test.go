@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/gong"
+	lib "github.com/libgit2/git2go/v31"
+)
+
+func TestStashCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		stashed bool
+	}{
+		{
+			name: `Command gong stash save <name>.
+				Should stash the uncommitted changes with <name>.`,
+			args:    []string{stashSaveCmd.Name(), "gong-wip"},
+			stashed: true,
+		},
+		{
+			name: `Command gong stash pop <name>.
+				Should apply the stash with <name> and drop it.`,
+			args:    []string{stashPopCmd.Name(), "gong-wip"},
+			stashed: false,
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	_, err = repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("%s/%s", repo.Path, "stash.me")
+	if err = ioutil.WriteFile(path, []byte("---i-am-untracked-and-i-shall-be-stashed---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	workdir := repo.Path
+
+	if err := os.Chdir(workdir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{stashCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			// Eat up the output
+			rootCmd.SetOut(bytes.NewBuffer(nil))
+
+			err = rootCmd.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := os.Stat(path)
+			if stashed := os.IsNotExist(err); stashed != tt.stashed {
+				t.Fatal(fmt.Errorf("file %s stashed %t, expected %t", path, stashed, tt.stashed))
+			}
+
+			stashes := gong.NewStashCollection(repo.Essence())
+
+			if _, err := stashes.Lookup("gong-wip"); (err == nil) != tt.stashed {
+				t.Fatal(fmt.Errorf("stash gong-wip exists %t, expected %t", err == nil, tt.stashed))
+			}
+		})
+	}
+}
+
+func TestStashSubcommandsCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	if _, err := repo.Seed(commitMsg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("%s/%s", repo.Path, "stash.me")
+	content := "---i-am-untracked-and-i-shall-be-stashed---\n"
+
+	stash := func(args ...string) string {
+		out := bytes.NewBuffer(nil)
+		rootCmd.SetOut(out)
+		rootCmd.SetErr(out)
+		defer rootCmd.SetErr(nil)
+
+		rootCmd.SetArgs(append([]string{stashCmd.Name()}, args...))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+
+		return out.String()
+	}
+
+	save := func(name string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		stash(stashSaveCmd.Name(), name)
+	}
+
+	exists := func(name string) bool {
+		_, err := gong.NewStashCollection(repo.Essence()).Lookup(name)
+		return err == nil
+	}
+
+	save("gong-wip")
+
+	t.Run("Command gong stash list. Should list the named stash", func(t *testing.T) {
+		if out := stash(stashListCmd.Name()); !strings.Contains(out, "named gong-wip") {
+			t.Fatalf("expected gong-wip to be listed, got %q", out)
+		}
+	})
+
+	t.Run("Command gong stash show <name>. Should show the stashed untracked file", func(t *testing.T) {
+		out := stash(stashShowCmd.Name(), "gong-wip")
+
+		if !strings.Contains(out, "stash.me") || !strings.Contains(out, strings.TrimSpace(content)) {
+			t.Fatalf("expected the diff of stash.me, got %q", out)
+		}
+	})
+
+	t.Run("Command gong stash apply <name>. Should apply the stash and keep it", func(t *testing.T) {
+		stash(stashApplyCmd.Name(), "gong-wip")
+
+		if _, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		}
+
+		if !exists("gong-wip") {
+			t.Fatal("expected stash gong-wip to be kept")
+		}
+
+		if err := os.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Command gong stash drop <name>. Should drop the stash without applying it", func(t *testing.T) {
+		stash(stashDropCmd.Name(), "gong-wip")
+
+		if exists("gong-wip") {
+			t.Fatal("expected stash gong-wip to be dropped")
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatal("expected the dropped stash not to be applied")
+		}
+	})
+
+	t.Run("Command gong stash branch <branchname> <name>. Should pop the stash onto a new branch", func(t *testing.T) {
+		save("gong-wip")
+
+		stash(stashBranchCmd.Name(), "gong-stash-branch", "gong-wip")
+
+		current, err := repo.CurrentBranch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(current)
+
+		if current.Name != "gong-stash-branch" {
+			t.Fatalf("expected to be on gong-stash-branch, got %s", current.Name)
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		}
+
+		if exists("gong-wip") {
+			t.Fatal("expected stash gong-wip to be popped")
+		}
+	})
+
+	t.Run("Command gong stash list with a stash dropped outside gong. Should report the dangling stash", func(t *testing.T) {
+		save("gong-dangling")
+
+		if err := repo.Essence().Stashes.Drop(0); err != nil {
+			t.Fatal(err)
+		}
+
+		if out := stash(stashListCmd.Name()); !strings.Contains(out, "gong-dangling (dropped outside gong") {
+			t.Fatalf("expected gong-dangling to be reported as dangling, got %q", out)
+		}
+
+		stash(stashDropCmd.Name(), "gong-dangling")
+
+		if dangling := gong.NewStashCollection(repo.Essence()).Dangling(); len(dangling) != 0 {
+			t.Fatalf("expected the dangling binding to be removed, got %v", dangling)
+		}
+	})
+
+	t.Run("Command gong stash branch <branchname> <name> with a stash bound to <branchname>. Should refuse to create the branch", func(t *testing.T) {
+		save("gong-wip")
+
+		stashes := gong.NewStashCollection(repo.Essence())
+
+		wip, err := stashes.Lookup("gong-wip")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The stash is left bound to the name of a branch that does not exist.
+		if err := stashes.Bind("gong-taken", wip.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		out := stash(stashBranchCmd.Name(), "gong-taken", "gong-wip")
+
+		if !strings.Contains(out, "branch gong-taken already has stashed changes") {
+			t.Fatalf("expected the branch to be refused, got %q", out)
+		}
+
+		if _, err := repo.FindBranch("gong-taken", lib.BranchLocal); err == nil {
+			t.Fatal("expected branch gong-taken not to be created")
+		}
+
+		if !exists("gong-wip") {
+			t.Fatal("expected stash gong-wip to be kept")
+		}
+	})
+}
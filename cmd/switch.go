@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/erikjuhani/git-gong/gong"
 	"github.com/spf13/cobra"
 )
@@ -14,25 +16,165 @@ func init() {
 		switchTagCmd,
 		switchReleaseCmd,
 	)
+
+	switchFlags()
 }
 
+var (
+	switchContinue bool
+	switchAbort    bool
+	switchCreate   bool
+	switchWorktree bool
+	switchCarry    bool
+)
+
 var switchCmd = &cobra.Command{
-	Use:   "switch [subcommand]",
+	Use:   "switch [target]",
 	Short: "Switch to branches, commits, tags or releases.",
-	Long:  ``,
-	Args:  cobra.ExactArgs(1),
+	Long: `Switch to branches, commits, tags or releases.
+
+  Without a subcommand the target is resolved in order as a local branch, a
+  remote branch, a release, a tag and a commit, and switched to. Use --create to create a
+  new branch from the current commit and switch to it. Without target a
+  branch, tag, release or recent commit can be picked interactively in a
+  terminal.
+
+  If the stash of the branch switched to conflicts, the switch stops and leaves
+  the conflict markers in place. Resolve the conflicts and finish the switch with
+  gong switch --continue, or return to the previous branch and restore its
+  changes with gong switch --abort.
+
+  gong switch - switches back to the previously checked-out branch or detached
+  commit. gong switch @{-N} switches to the Nth previous one.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if switchContinue || switchAbort {
+			return cobra.NoArgs(cmd, args)
+		}
+
+		if switchCreate {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+
+		return pickOr(cobra.ExactArgs(1))(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		if switchAbort {
+			branch, err := repo.AbortSwitch()
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+			defer gong.Free(branch)
+
+			cmd.Printf("switch aborted, checkout to branch %s\n", branch.Name)
+			return
+		}
+
+		if switchContinue {
+			branch, err := repo.ContinueSwitch()
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+			defer gong.Free(branch)
+
+			cmd.Printf("checkout to branch %s\n", branch.Name)
+			return
+		}
+
+		if len(args) == 0 {
+			items, err := targetPickItems(repo)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			target, err := pickTarget(cmd, "switch to", items)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			args = []string{target}
+		}
+
+		if previous, ok := gong.ParsePreviousCheckout(args[0]); ok && !switchCreate {
+			checkout, err := repo.CheckoutPrevious(previous)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			if checkout.IsDetached() {
+				cmd.Printf("switched to commit %s\n", checkout.Commit)
+				return
+			}
+
+			cmd.Printf("checkout to branch %s\n", checkout.Branch)
+			return
+		}
+
+		revision, err := repo.Switch(args[0], switchCreate)
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(revision)
+
+		switch {
+		case switchCreate:
+			cmd.Printf("created branch %s\n", revision.Name)
+			cmd.Printf("checkout to branch %s\n", revision.Name)
+		case revision.Kind == gong.RevisionBranch:
+			cmd.Printf("%s resolved to branch %s\n", args[0], revision.Name)
+			cmd.Printf("checkout to branch %s\n", revision.Name)
+		case revision.Kind == gong.RevisionRemoteBranch:
+			cmd.Printf("%s resolved to remote branch %s\n", args[0], revision.Name)
+			cmd.Printf("checkout to branch %s tracking %s\n", args[0], revision.Name)
+		case revision.Kind == gong.RevisionRelease:
+			cmd.Printf("%s resolved to release %s\n", args[0], revision.Name)
+			cmd.Printf("checkout to release %s\n", revision.Name)
+		case revision.Kind == gong.RevisionTag:
+			cmd.Printf("%s resolved to tag %s\n", args[0], revision.Name)
+			cmd.Printf("checkout to tag %s\n", revision.Name)
+		default:
+			cmd.Printf("%s resolved to %s %s\n", args[0], revision.Kind, revision.Name)
+			cmd.Printf("switched to commit %s\n", revision.Commit.ID.String())
+		}
 	},
 }
 
 var switchBranchCmd = &cobra.Command{
-	Use:   "branch [branchname]",
+	Use:   "branch [branchname] [path]",
 	Short: "Switch to branch with branchname.",
 	Long: `If branchname does not exist create branch with branchname.
 		if there are any unsaved changes stash them to @<previousbranchname>.
 		When switching to branch check if there exists a stash and pop the stash.
+		Branchname @{-N} refers to the Nth previously checked-out branch.
+		Without branchname the branch can be picked interactively in a terminal.
+
+		With --worktree the current checkout is left untouched and the branch is
+		checked out to a linked worktree at path instead, by default next to the
+		repository. If the branch already has a worktree it is reused.
+
+		With --carry the uncommitted changes, including untracked files, are not
+		stashed but carried onto the branch with a three-way merge. If the changes
+		conflict with the branch the switch is aborted and nothing is changed.
 	`,
-	Args: cobra.ExactArgs(1),
+	Args: pickOr(func(cmd *cobra.Command, args []string) error {
+		if switchWorktree {
+			return cobra.RangeArgs(1, 2)(cmd, args)
+		}
+
+		return cobra.ExactArgs(1)(cmd, args)
+	}),
 	Run: func(cmd *cobra.Command, args []string) {
 		repo, err := gong.Open()
 		if err != nil {
@@ -41,22 +183,95 @@ var switchBranchCmd = &cobra.Command{
 		}
 		defer gong.Free(repo)
 
-		branch, err := repo.CheckoutBranch(args[0])
+		if len(args) == 0 {
+			items, err := branchPickItems(repo, true, true)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			branchName, err := pickTarget(cmd, "switch to branch", items)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			args = []string{branchName}
+		}
+
+		branchName := args[0]
+
+		if n, ok := gong.ParsePreviousCheckout(branchName); ok {
+			checkout, err := repo.PreviousCheckout(n)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			if checkout.IsDetached() {
+				cmd.PrintErr(fmt.Errorf("%s is the detached commit %s, use gong switch %s", branchName, checkout.Commit, branchName))
+				return
+			}
+
+			branchName = checkout.Branch
+		}
+
+		if switchWorktree && switchCarry {
+			cmd.PrintErr(fmt.Errorf("--carry cannot be used with --worktree"))
+			return
+		}
+
+		if switchCarry {
+			branch, err := repo.CarryBranch(branchName)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+			defer gong.Free(branch)
+
+			cmd.Printf("checkout to branch %s with the uncommitted changes\n", branchName)
+			return
+		}
+
+		if switchWorktree {
+			path := ""
+			if len(args) > 1 {
+				path = args[1]
+			}
+
+			worktree, created, err := repo.SwitchWorktree(branchName, path)
+			if err != nil {
+				cmd.PrintErr(err)
+				return
+			}
+
+			if created {
+				cmd.Printf("created worktree for branch %s at %s\n", branchName, worktree.Path)
+				return
+			}
+
+			cmd.Printf("branch %s is checked out in worktree %s\n", branchName, worktree.Path)
+			return
+		}
+
+		branch, err := repo.CheckoutBranch(branchName)
 		if err != nil {
 			cmd.PrintErr(err)
 			return
 		}
 		defer gong.Free(branch)
 
-		cmd.Printf("checkout to branch %s\n", args[0])
+		cmd.Printf("checkout to branch %s\n", branchName)
 	},
 }
 
 var switchCommitCmd = &cobra.Command{
-	Use:   "commit [commithash]",
+	Use:   "commit [revision]",
 	Short: "Switch to commit.",
-	Long:  ``,
-	Args:  cobra.ExactArgs(1),
+	Long: `Switch to the commit the revision resolves to. The revision can be a full or
+  abbreviated commit hash, a branch, remote branch or tag, and can use the
+  revision operators HEAD~n, ^n, @{-n}, @{n}, <ref>@{date} and :/message.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		repo, err := gong.Open()
 		if err != nil {
@@ -72,7 +287,7 @@ var switchCommitCmd = &cobra.Command{
 		}
 		defer gong.Free(commit)
 
-		cmd.Printf("switched to commit %s\n", args[0])
+		cmd.Printf("switched to commit %s\n", commit.ID.String())
 	},
 }
 
@@ -103,8 +318,11 @@ var switchTagCmd = &cobra.Command{
 var switchReleaseCmd = &cobra.Command{
 	Use:   "release [release]",
 	Short: "Switch to release.",
-	Long:  ``,
-	Args:  cobra.ExactArgs(1),
+	Long: `Switch to a release. Release can be a release name e.g. v1.2.3, a version
+  e.g. 1.2.3, latest for the highest version that is not a prerelease, or a
+  version range such as ^1.2 or ~1.2.3 that resolves to the highest matching
+  version. Tags that are not releases cannot be switched to with this command.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		repo, err := gong.Open()
 		if err != nil {
@@ -113,13 +331,36 @@ var switchReleaseCmd = &cobra.Command{
 		}
 		defer gong.Free(repo)
 
-		tag, err := repo.CheckoutTag(args[0])
+		release, err := repo.CheckoutRelease(args[0])
 		if err != nil {
 			cmd.PrintErr(err)
 			return
 		}
-		defer gong.Free(tag)
+		defer gong.Free(release)
 
-		cmd.Printf("checkout to release %s\n", tag.Name)
+		cmd.Printf("checkout to release %s\n", release.Name)
 	},
 }
+
+func switchFlags() {
+	switchCmd.Flags().BoolVar(
+		&switchContinue, "continue", false,
+		"Finish a switch stopped by stash conflicts after the conflicts have been resolved",
+	)
+	switchCmd.Flags().BoolVar(
+		&switchAbort, "abort", false,
+		"Abort a switch stopped by stash conflicts and return to the previous branch",
+	)
+	switchCmd.Flags().BoolVarP(
+		&switchCreate, "create", "c", false,
+		"Create a new branch with the target name and switch to it",
+	)
+	switchBranchCmd.Flags().BoolVar(
+		&switchWorktree, "worktree", false,
+		"Check out the branch to a linked worktree instead of the current checkout",
+	)
+	switchBranchCmd.Flags().BoolVar(
+		&switchCarry, "carry", false,
+		"Carry the uncommitted changes to the branch instead of stashing them",
+	)
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/erikjuhani/git-gong/gong"
@@ -68,15 +71,733 @@ func TestSwitchBranchCmd(t *testing.T) {
 	}
 }
 
+func TestSwitchBranchStashCmd(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: `Command gong switch branch <branchname>.
+				Should pop the stash of <branchname> when switching back to it,
+				even if <branchname> has received new commits in between.`,
+			args: []string{"main"},
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	_, err = repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stashed := fmt.Sprintf("%s/%s", repo.Path, "stash.me")
+	if err = ioutil.WriteFile(stashed, []byte("---i-am-untracked-and-i-shall-be-stashed---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.CheckoutBranch("gong-branch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(stashed); !os.IsNotExist(err) {
+		t.Fatal(fmt.Errorf("expected %s to be stashed", stashed))
+	}
+
+	commit, err := repo.Seed("gong-branch-commit", "commit.me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Move main forward, the stash should still be bound to main.
+	_, err = repo.Essence().References.Create("refs/heads/main", commit.ID, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workdir := repo.Path
+
+	if err := os.Chdir(workdir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{switchCmd.Name(), switchBranchCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			err = rootCmd.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := os.Stat(stashed); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSwitchDanglingStashCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	if _, err := repo.Seed(commitMsg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	stashed := fmt.Sprintf("%s/%s", repo.Path, "stash.me")
+
+	switchTo := func(branchName string) {
+		rootCmd.SetArgs([]string{switchCmd.Name(), switchBranchCmd.Name(), branchName})
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+
+		current, err := repo.CurrentBranch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(current)
+
+		if current.Name != branchName {
+			t.Fatalf("expected to switch to %s, current branch is %s", branchName, current.Name)
+		}
+	}
+
+	t.Run(`Command gong switch branch <branchname> with the stash of the current branch dropped outside gong.
+		Should ignore the dangling stash binding and stash the changes again.`, func(t *testing.T) {
+		if err := ioutil.WriteFile(stashed, []byte("first\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		switchTo("gong-branch")
+
+		// Drop the stash bound to main behind the back of gong.
+		if err := repo.Essence().Stashes.Drop(0); err != nil {
+			t.Fatal(err)
+		}
+
+		switchTo("main")
+
+		if _, err := os.Stat(stashed); !os.IsNotExist(err) {
+			t.Fatal("expected the dropped stash not to be applied")
+		}
+
+		if err := ioutil.WriteFile(stashed, []byte("second\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		switchTo("gong-branch")
+
+		stashes := gong.NewStashCollection(repo.Essence())
+
+		if len(stashes.Dangling()) != 0 {
+			t.Fatalf("expected the dangling binding to be replaced, got %v", stashes.Dangling())
+		}
+
+		if _, err := stashes.Lookup("main"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestSwitchConflictCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	conflicted := filepath.Join(repo.Path, "a.file")
+	untracked := filepath.Join(repo.Path, "untracked.me")
+
+	write := func(path string, content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(conflicted, "base\n")
+
+	tree, err := repo.AddToIndex([]string{"a.file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateCommit(tree, "base"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave changes on main to be stashed when switching away.
+	write(conflicted, "main-wip\n")
+	write(untracked, "untracked\n")
+
+	if _, err := repo.CheckoutBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	write(conflicted, "gong-branch\n")
+
+	tree, err = repo.AddToIndex([]string{"a.file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit, err := repo.CreateCommit(tree, "gong-branch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Move main forward so that the stash of main conflicts with it.
+	if _, err := repo.Essence().References.Create("refs/heads/main", commit.ID, true, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) {
+		rootCmd.SetArgs(append([]string{switchCmd.Name()}, args...))
+		rootCmd.SetOut(bytes.NewBuffer(nil))
+
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func() {
+		switchContinue = false
+		switchAbort = false
+	}()
+
+	stop := func() {
+		run(switchBranchCmd.Name(), "main")
+
+		conflicts, err := repo.Conflicts()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(conflicts) != 1 || conflicts[0] != "a.file" {
+			t.Fatalf("expected a.file to be conflicted, got %v", conflicts)
+		}
+
+		inProgress, err := repo.SwitchInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !inProgress {
+			t.Fatal("expected the switch to be in progress")
+		}
+	}
+
+	t.Run(`Command gong switch branch <branchname> with a conflicting stash.
+		Should stop with the conflict markers in place.`, func(t *testing.T) {
+		stop()
+	})
+
+	t.Run(`Command gong switch --abort.
+		Should return to the previous branch and keep the stash.`, func(t *testing.T) {
+		run("--abort")
+		switchAbort = false
+
+		current, err := repo.CurrentBranch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gong.Free(current)
+
+		if current.Name != "gong-branch" {
+			t.Fatalf("expected to return to gong-branch, got %s", current.Name)
+		}
+
+		if _, err := os.Stat(untracked); !os.IsNotExist(err) {
+			t.Fatal("expected the stashed untracked file to be removed")
+		}
+
+		if _, err := gong.NewStashCollection(repo.Essence()).Lookup("main"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run(`Command gong switch --continue.
+		Should drop the stash and leave the resolved changes unstaged.`, func(t *testing.T) {
+		// A named stash with the name of the branch is not the stash of the switch.
+		write(filepath.Join(repo.Path, "named.me"), "named\n")
+
+		if _, err := gong.NewStashCollection(repo.Essence()).Save("main"); err != nil {
+			t.Fatal(err)
+		}
+
+		stop()
+
+		write(conflicted, "resolved\n")
+
+		run("--continue")
+		switchContinue = false
+
+		inProgress, err := repo.SwitchInProgress()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if inProgress {
+			t.Fatal("expected the switch to be finished")
+		}
+
+		stashes := gong.NewStashCollection(repo.Essence())
+
+		if _, ok := stashes.Stashes()["main"]; ok {
+			t.Fatal("expected the stash of main to be dropped")
+		}
+
+		named, err := stashes.Lookup("main")
+		if err != nil || named.IsAuto() {
+			t.Fatalf("expected the named stash main to be kept, got %v", err)
+		}
+
+		status, err := repo.Essence().StatusFile("a.file")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if status != lib.StatusWtModified {
+			t.Fatalf("expected a.file to be modified in the working tree only, got status %v", status)
+		}
+
+		if _, err := os.Stat(untracked); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestSwitchPreviousCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: `Command gong switch branch <branchname>.
+				Should remember the branch switched from.`,
+			args:     []string{switchBranchCmd.Name(), "gong-branch"},
+			expected: "gong-branch",
+		},
+		{
+			name: `Command gong switch -.
+				Should switch back to the previously checked-out branch.`,
+			args:     []string{"-"},
+			expected: "main",
+		},
+		{
+			name: `Command gong switch branch @{-N}.
+				Should switch to the Nth previously checked-out branch.`,
+			args:     []string{switchBranchCmd.Name(), "@{-1}"},
+			expected: "gong-branch",
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	_, err = repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{switchCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			branch, err := repo.CurrentBranch()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if branch.Name != tt.expected {
+				t.Fatalf("current branch %s does not equal to expected branch %s", branch.Name, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSwitchRemoteBranchCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: `Command gong switch branch <branchname>.
+				Should create <branchname> from the matching remote branch and
+				track it, when <branchname> does not exist locally.`,
+			args:     []string{"gong-branch"},
+			expected: "origin/gong-branch",
+		},
+		{
+			name: `Command gong switch branch <branchname>.
+				Should not create <branchname> when the name matches branches
+				on more than one remote.`,
+			args: []string{"shared-branch"},
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	remoteCommit, err := repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, remoteName := range []string{"origin", "upstream"} {
+		remote, err := repo.Essence().Remotes.Create(remoteName, fmt.Sprintf("https://example.com/%s/gong.git", remoteName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer remote.Free()
+
+		_, err = repo.Essence().References.Create(fmt.Sprintf("refs/remotes/%s/shared-branch", remoteName), remoteCommit.ID, false, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = repo.Essence().References.Create("refs/remotes/origin/gong-branch", remoteCommit.ID, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.Seed("second", "commit.me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{switchCmd.Name(), switchBranchCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			branch, err := repo.FindBranch(tt.args[0], lib.BranchLocal)
+
+			if tt.expected == "" {
+				if err == nil {
+					t.Fatalf("ambiguous branch %s should not have been created", tt.args[0])
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if branch.Upstream != tt.expected {
+				t.Fatalf("upstream %s does not equal to expected upstream %s", branch.Upstream, tt.expected)
+			}
+
+			if !branch.ReferenceID.Equal(remoteCommit.ID) {
+				t.Fatalf("branch %s does not point to the remote branch commit", tt.args[0])
+			}
+		})
+	}
+}
+
+func TestSwitchTargetCmd(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		expectedBranch string
+		detached       bool
+	}{
+		{
+			name: `Command gong switch <target>.
+				Should switch to the local branch when target is a local branch.`,
+			args:           []string{"gong-branch"},
+			expectedBranch: "gong-branch",
+		},
+		{
+			name: `Command gong switch <target>.
+				Should switch to the tag when target is a tag.`,
+			args:     []string{"v0.1.0"},
+			detached: true,
+		},
+		{
+			name: `Command gong switch <target>.
+				Should switch to the branch when target is main.`,
+			args:           []string{"main"},
+			expectedBranch: "main",
+		},
+		{
+			name: `Command gong switch <target>.
+				Should switch to the commit when target is a revision.`,
+			args:     []string{"HEAD~1"},
+			detached: true,
+		},
+		{
+			name: `Command gong switch --create <target>.
+				Should create a new branch and switch to it.`,
+			args:           []string{"--create", "new-branch"},
+			expectedBranch: "new-branch",
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	firstCommit, err := repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("v0.1.0", "first release"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.Seed("second", "commit.me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateLocalBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { switchCreate = false }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{switchCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			detached, err := repo.Head.IsDetached()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if detached != tt.detached {
+				t.Fatalf("head detached %t does not equal to expected %t", detached, tt.detached)
+			}
+
+			if detached {
+				commit, err := repo.Head.Commit()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !commit.ID.Equal(firstCommit.ID) {
+					t.Fatalf("current commit %s does not equal to expected commit %s", commit.ID, firstCommit.ID)
+				}
+				return
+			}
+
+			branch, err := repo.CurrentBranch()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if branch.Name != tt.expectedBranch {
+				t.Fatalf("current branch %s does not equal to expected branch %s", branch.Name, tt.expectedBranch)
+			}
+		})
+	}
+}
+
+func TestSwitchBranchCarryCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: `Command gong switch branch <branchname> --carry.
+				Should abort and keep the changes when they conflict with <branchname>.`,
+			args:     []string{"gong-conflict", "--carry"},
+			expected: "main",
+		},
+		{
+			name: `Command gong switch branch <branchname> --carry.
+				Should carry the uncommitted changes including untracked files to <branchname>.`,
+			args:     []string{"gong-branch", "--carry"},
+			expected: "gong-branch",
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	_, err = repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateLocalBranch("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("gong-conflict"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Seed("conflict", "carry.me"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A named stash with the name of the branch is not the carried stash.
+	if err := ioutil.WriteFile(filepath.Join(repo.Path, "named.me"), []byte("named\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := gong.NewStashCollection(repo.Essence()).Save("gong-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	carried := []byte("---i-am-untracked-and-i-shall-be-carried---\n")
+
+	path := fmt.Sprintf("%s/%s", repo.Path, "carry.me")
+	if err = ioutil.WriteFile(path, carried, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { switchCarry = false }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{switchCmd.Name(), switchBranchCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			branch, err := repo.CurrentBranch()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if branch.Name != tt.expected {
+				t.Fatalf("current branch %s does not equal to expected branch %s", branch.Name, tt.expected)
+			}
+
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(content) != string(carried) {
+				t.Fatalf("carried file content %q does not equal to expected content %q", content, carried)
+			}
+
+			if _, err := repo.Essence().References.Lookup("refs/gong/stash/main"); err == nil {
+				t.Fatal("changes should not have been stashed to branch main")
+			}
+
+			for _, branchName := range []string{"gong-conflict", "gong-branch"} {
+				if _, err := repo.Essence().References.Lookup("refs/gong/carry/" + branchName); err == nil {
+					t.Fatalf("the carried stash to %s should have been removed", branchName)
+				}
+			}
+
+			named, err := gong.NewStashCollection(repo.Essence()).Lookup("gong-branch")
+			if err != nil || named.IsAuto() {
+				t.Fatalf("the named stash gong-branch should have been kept, got %v", err)
+			}
+		})
+	}
+}
+
 func TestSwitchCommitCmd(t *testing.T) {
 	tests := []struct {
 		name string
+		rev  func(id string) string
 	}{
 		{
 			name: `Command gong switch commit <commithash>.
 				Should change to a commit with <commithash>. e.g.
 				gong switch commit abc3 -> Should change to commit abc3 in the
 				currently checked out branch.`,
+			rev: func(id string) string { return id },
+		},
+		{
+			name: `Command gong switch commit <shorthash>.
+				Should change to the commit with the abbreviated hash.`,
+			rev: func(id string) string { return id[:7] },
+		},
+		{
+			name: `Command gong switch commit <revision>.
+				Should change to the commit the revision resolves to. e.g.
+				gong switch commit main~1 -> Should change to the parent of main.`,
+			rev: func(string) string { return "main~1" },
 		},
 	}
 
@@ -107,7 +828,7 @@ func TestSwitchCommitCmd(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			args := []string{switchCmd.Name(), switchCommitCmd.Name()}
-			args = append(args, expectedID)
+			args = append(args, tt.rev(expectedID))
 
 			rootCmd.SetArgs(args)
 
@@ -129,6 +850,212 @@ func TestSwitchCommitCmd(t *testing.T) {
 		})
 	}
 }
+func TestSwitchCommitRevisionCmd(t *testing.T) {
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	first, err := repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Essence().References.Create("refs/remotes/origin/remote-branch", first.ID, false, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateLocalBranch("feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	featureCommit, err := repo.Seed("feature work", "feature.file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CheckoutBranch("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := repo.Seed("second", "commit.me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("tagged", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("twin", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateLocalBranch("twin"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Merge("feature", gong.MergeNoFastForward); err != nil {
+		t.Fatal(err)
+	}
+
+	mergeCommit, err := repo.Head.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ambiguousHash := ambiguousObjectPrefix(t, repo.Repository)
+
+	tests := []struct {
+		name     string
+		rev      string
+		before   func() error
+		expected *lib.Oid
+		err      string
+	}{
+		{
+			name: `Command gong switch commit <ref>^<n>.
+				Should change to the nth parent of the commit.`,
+			rev:      "main^2",
+			expected: featureCommit.ID,
+		},
+		{
+			name: `Command gong switch commit <tag>.
+				Should change to the commit of the tag.`,
+			rev:      "tagged",
+			expected: second.ID,
+		},
+		{
+			name: `Command gong switch commit <remote>/<branch>.
+				Should change to the commit of the remote branch.`,
+			rev:      "origin/remote-branch",
+			expected: first.ID,
+		},
+		{
+			name: `Command gong switch commit @{-1}.
+				Should change to the previously checked-out branch.`,
+			rev: "@{-1}",
+			before: func() error {
+				if _, err := repo.CheckoutBranch("feature"); err != nil {
+					return err
+				}
+				_, err := repo.CheckoutBranch("main")
+				return err
+			},
+			expected: featureCommit.ID,
+		},
+		{
+			name: `Command gong switch commit <ref>@{<n>}.
+				Should change to the nth prior value of the reference.`,
+			rev:      "main@{1}",
+			expected: second.ID,
+		},
+		{
+			name: `Command gong switch commit <ref>@{<date>}.
+				Should change to the value the reference had at the date.`,
+			rev:      "main@{now}",
+			expected: mergeCommit.ID,
+		},
+		{
+			name: `Command gong switch commit :/<message>.
+				Should change to the youngest commit with a matching message.`,
+			rev:      ":/feature work",
+			expected: featureCommit.ID,
+		},
+		{
+			name: `Command gong switch commit <name>.
+				Should not change the commit when the name is both a branch
+				and a tag.`,
+			rev: "twin",
+			err: "could be heads/twin, tags/twin",
+		},
+		{
+			name: `Command gong switch commit <shorthash>.
+				Should not change the commit when the abbreviated hash matches
+				more than one object.`,
+			rev: ambiguousHash,
+			err: "matches more than one",
+		},
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.CheckoutBranch("main"); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.before != nil {
+				if err := tt.before(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			errOut := bytes.NewBuffer(nil)
+
+			rootCmd.SetArgs([]string{switchCmd.Name(), switchCommitCmd.Name(), tt.rev})
+			rootCmd.SetOut(bytes.NewBuffer(nil))
+			rootCmd.SetErr(errOut)
+			defer rootCmd.SetErr(nil)
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			currentTip, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer gong.Free(currentTip)
+
+			if tt.err != "" {
+				if !strings.Contains(errOut.String(), tt.err) {
+					t.Fatalf("error %q does not contain %q", errOut.String(), tt.err)
+				}
+
+				if !currentTip.ID.Equal(mergeCommit.ID) {
+					t.Fatalf("head moved to %s on an ambiguous revision", currentTip.ID)
+				}
+				return
+			}
+
+			if errOut.Len() > 0 {
+				t.Fatal(errOut.String())
+			}
+
+			if !currentTip.ID.Equal(tt.expected) {
+				t.Fatalf("current tip %s does not equal to expected tip %s", currentTip.ID, tt.expected)
+			}
+		})
+	}
+}
+
+// ambiguousObjectPrefix writes blobs until two of them share an abbreviated
+// hash, and returns the shared prefix.
+func ambiguousObjectPrefix(t *testing.T, repo *gong.Repository) string {
+	seen := make(map[string]bool)
+
+	for i := 0; ; i++ {
+		id, err := repo.Essence().CreateBlobFromBuffer([]byte(fmt.Sprintf("blob %d\n", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		prefix := id.String()[:4]
+		if seen[prefix] {
+			return prefix
+		}
+		seen[prefix] = true
+	}
+}
 
 func TestSwitchTagCmd(t *testing.T) {
 	tests := []struct {
@@ -261,3 +1188,87 @@ func TestSwitchReleaseCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestSwitchReleaseRangeCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: `Command gong switch release latest.
+				Should change to the release with the highest version.`,
+			args:     []string{"latest"},
+			expected: "v2.0.0",
+		},
+		{
+			name: `Command gong switch release <range>.
+				Should change to the highest release matching the caret range.`,
+			args:     []string{"^1.1"},
+			expected: "v1.2.0",
+		},
+		{
+			name: `Command gong switch release <range>.
+				Should change to the highest release matching the tilde range.`,
+			args:     []string{"~1.1"},
+			expected: "v1.1.0",
+		},
+		{
+			name: `Command gong switch release <tag>.
+				Should not change to a tag that is not a release.`,
+			args:     []string{"snapshot"},
+			expected: "v1.1.0",
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	commits := make(map[string]string)
+
+	for i, releaseName := range []string{"v1.1.0", "v1.2.0", "v2.0.0"} {
+		commit, err := repo.Seed(releaseName, fmt.Sprintf("release-%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.CreateRelease(releaseName, "", gong.DefaultReleaseChannel); err != nil {
+			t.Fatal(err)
+		}
+
+		commits[releaseName] = commit.ID.String()
+	}
+
+	if _, err := repo.CreateTag("snapshot", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(repo.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{switchCmd.Name(), switchReleaseCmd.Name()}
+			args = append(args, tt.args...)
+
+			rootCmd.SetArgs(args)
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			currentTip, err := repo.Head.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if currentTip.ID.String() != commits[tt.expected] {
+				t.Fatalf("current tip %s does not equal to the commit of release %s", currentTip.ID, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"github.com/erikjuhani/git-gong/gong"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(trackCmd, untrackCmd)
+}
+
+var trackCmd = &cobra.Command{
+	Use:   "track [remote/branchname]",
+	Short: "Set the upstream of the current branch.",
+	Long: `Set the remote branch e.g. origin/main as the upstream of the current branch.
+
+  To set the upstream automatically on the first push of a branch enable
+  auto_set_upstream in .gong/config:
+  [push]
+  auto_set_upstream = true`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		branch, err := repo.Track(args[0])
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(branch)
+
+		cmd.Printf("branch %s tracks %s\n", branch.Name, branch.Upstream)
+	},
+}
+
+var untrackCmd = &cobra.Command{
+	Use:   "untrack",
+	Short: "Remove the upstream of the current branch.",
+	Long:  ``,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := gong.Open()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(repo)
+
+		branch, err := repo.Untrack()
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+		defer gong.Free(branch)
+
+		cmd.Printf("branch %s does not track an upstream anymore\n", branch.Name)
+	},
+}
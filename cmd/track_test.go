@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/erikjuhani/git-gong/gong"
+	lib "github.com/libgit2/git2go/v31"
+)
+
+func TestTrackCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: `Command gong track <remote/branchname>.
+				Should set <remote/branchname> as the upstream of the current branch.`,
+			args:     []string{trackCmd.Name(), "origin/main"},
+			expected: "origin/main",
+		},
+		{
+			name: `Command gong untrack.
+				Should remove the upstream of the current branch.`,
+			args:     []string{untrackCmd.Name()},
+			expected: "",
+		},
+	}
+
+	repo, clean, err := gong.TestRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+
+	commit, err := repo.Seed(commitMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := repo.Essence().Remotes.Create("origin", "https://example.com/gong.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Free()
+
+	_, err = repo.Essence().References.Create("refs/remotes/origin/main", commit.ID, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workdir := repo.Path
+
+	if err := os.Chdir(workdir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootCmd.SetArgs(tt.args)
+
+			// Eat up the output
+			rootCmd.SetOut(bytes.NewBuffer(nil))
+
+			err = rootCmd.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			branch, err := repo.FindBranch("main", lib.BranchLocal)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if branch.Upstream != tt.expected {
+				t.Fatal(fmt.Errorf("upstream %s does not equal to expected upstream %s", branch.Upstream, tt.expected))
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ var undoCmd = &cobra.Command{
 	Short: "Undo undoes the last command of the user.",
 	Long: `If user for example has made a mistake commit gong commit -m "mistake"
 		the undo, undoes the commit command and sets the repository to a prior state.
+		Undo rewrites the history, use gong revert for commits that are already shared.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		repo, err := gong.Open()
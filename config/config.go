@@ -1,9 +1,11 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/erikjuhani/git-gong/fs"
@@ -15,6 +17,25 @@ type ConfigKey = string
 const (
 	AllowedBranchPatternsKey   ConfigKey = "rules.allowed_branch_patterns"
 	ProtectedBranchPatternsKey ConfigKey = "rules.protected_branch_patterns"
+	BranchTemplatesKey         ConfigKey = "templates.branch"
+	AutoSetUpstreamKey         ConfigKey = "push.auto_set_upstream"
+	ReleasePatternKey          ConfigKey = "releases.pattern"
+	MergeConflictStyleKey      ConfigKey = "merge.conflict_style"
+	MergeStrategiesKey         ConfigKey = "merge.strategies"
+	MergePoliciesKey           ConfigKey = "merge.policies"
+)
+
+// DefaultReleasePattern is the release tag name pattern used when none has been
+// configured. {version} stands for a semantic version e.g. 1.2.3 or 1.2.3-rc.1.
+const DefaultReleasePattern = "v{version}"
+
+// Conflict styles of the conflict markers written to the working tree. The
+// diff3 style includes the common ancestor of the conflicting changes. zdiff3
+// is recognized only to be refused, as libgit2 cannot write it.
+const (
+	ConflictStyleMerge  = "merge"
+	ConflictStyleDiff3  = "diff3"
+	ConflictStyleZDiff3 = "zdiff3"
 )
 
 const (
@@ -22,9 +43,12 @@ const (
 	configType = "toml"
 )
 
-var initFns = []func(){
+var initFns = []func() error{
 	genAllowedBranchPatterns,
 	genProtectedBranchPatterns,
+	genBranchTemplates,
+	genMergeStrategies,
+	genMergePolicies,
 }
 
 type Patterns []*regexp.Regexp
@@ -52,24 +76,253 @@ var (
 	ProtectedBranchPatterns = &Patterns{}
 )
 
+// BranchTemplates maps a branch type e.g. feature to a branch name template
+// e.g. feature/{ticket}-{description}.
+var BranchTemplates = map[string]string{}
+
+// IsProtectedBranch reports whether the branch name matches any of the
+// configured protected branch patterns. No patterns protect no branches, unlike
+// Patterns.Match which matches every name when there are no patterns. Commits,
+// rebases, picks, reverts and prunes all check protection with it. Before, an
+// empty rules.protected_branch_patterns refused commits on every branch.
+func IsProtectedBranch(branchName string) bool {
+	return len(*ProtectedBranchPatterns) > 0 && ProtectedBranchPatterns.Match(branchName)
+}
+
+// MergeStrategies maps a target branch name or a glob pattern e.g. release/*
+// to the default merge strategy of merges into the branch.
+var MergeStrategies = map[string]string{}
+
+// MergeStrategyFor returns the configured default merge strategy of merges
+// into the branch. The branch is matched to the keys like in matchBranchKey.
+// An empty string is returned if no strategy has been configured.
+func MergeStrategyFor(branchName string) string {
+	var keys []string
+	for key := range MergeStrategies {
+		keys = append(keys, key)
+	}
+
+	if key, ok := matchBranchKey(keys, branchName); ok {
+		return MergeStrategies[key]
+	}
+
+	return ""
+}
+
+// MergePolicy restricts merges into the branches it applies to. Sources are
+// the branch name patterns that may be merged, Strategies the merge strategies
+// that may be used and StatusCommand a shell command that has to succeed
+// before merging. Empty fields do not restrict merges.
+type MergePolicy struct {
+	Sources       []string `mapstructure:"sources"`
+	Strategies    []string `mapstructure:"strategies"`
+	StatusCommand string   `mapstructure:"status_command"`
+}
+
+// AllowsSource reports whether the source branch name matches any of the
+// source patterns of the policy.
+func (policy MergePolicy) AllowsSource(branchName string) bool {
+	if len(policy.Sources) == 0 {
+		return true
+	}
+
+	_, ok := matchBranchKey(policy.Sources, branchName)
+	return ok
+}
+
+// AllowsStrategy reports whether the policy allows merging with the strategy.
+func (policy MergePolicy) AllowsStrategy(strategy string) bool {
+	if len(policy.Strategies) == 0 {
+		return true
+	}
+
+	for _, allowed := range policy.Strategies {
+		if allowed == strategy {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MergePolicies maps a target branch name or a glob pattern e.g. release/*
+// to the policy of merges into the branch.
+var MergePolicies = map[string]MergePolicy{}
+
+// MergePolicyFor returns the merge policy of merges into the branch. The
+// branch is matched to the keys like in matchBranchKey. False is returned if
+// no policy has been configured.
+func MergePolicyFor(branchName string) (MergePolicy, bool) {
+	var keys []string
+	for key := range MergePolicies {
+		keys = append(keys, key)
+	}
+
+	if key, ok := matchBranchKey(keys, branchName); ok {
+		return MergePolicies[key], true
+	}
+
+	return MergePolicy{}, false
+}
+
+// matchBranchKey returns the key that is equal to the branch name, or else
+// the most specific key that matches it as a glob-like pattern, where *
+// matches any characters and the rest of the pattern matches literally. A
+// pattern matches the whole name, e.g. release/* matches release/1.0 but not
+// old-release/1.0. The longer the literal prefix before the first * of a
+// pattern is the more specific it is, e.g. release/v1.* is preferred over
+// release/*. Patterns with equally long prefixes are preferred in sorted order.
+func matchBranchKey(keys []string, branchName string) (string, bool) {
+	var matched []string
+
+	for _, key := range keys {
+		if key == branchName {
+			return key, true
+		}
+
+		glob, err := branchGlob(key)
+		if err != nil {
+			continue
+		}
+
+		if glob.MatchString(branchName) {
+			matched = append(matched, key)
+		}
+	}
+
+	if len(matched) == 0 {
+		return "", false
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		iPrefix, jPrefix := literalPrefixLen(matched[i]), literalPrefixLen(matched[j])
+		if iPrefix != jPrefix {
+			return iPrefix > jPrefix
+		}
+
+		return matched[i] < matched[j]
+	})
+
+	return matched[0], true
+}
+
+// branchGlob compiles the glob-like pattern to a regular expression matching
+// the whole branch name.
+func branchGlob(pattern string) (*regexp.Regexp, error) {
+	parts := strings.Split(pattern, "*")
+
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	return regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// literalPrefixLen returns the length of the pattern before its first *.
+func literalPrefixLen(pattern string) int {
+	if i := strings.Index(pattern, "*"); i >= 0 {
+		return i
+	}
+
+	return len(pattern)
+}
+
 func Get(key ConfigKey) interface{} {
 	return viper.Get(key)
 }
 
-func genAllowedBranchPatterns() {
+func GetStringSlice(key ConfigKey) []string {
+	return viper.GetStringSlice(key)
+}
+
+func GetBool(key ConfigKey) bool {
+	return viper.GetBool(key)
+}
+
+func GetString(key ConfigKey) string {
+	return viper.GetString(key)
+}
+
+func genAllowedBranchPatterns() error {
 	patterns := viper.GetStringSlice(AllowedBranchPatternsKey)
 
 	for _, pattern := range patterns {
 		AllowedBranchPatterns.AddPattern(pattern)
 	}
+
+	return nil
 }
 
-func genProtectedBranchPatterns() {
+func genProtectedBranchPatterns() error {
 	patterns := viper.GetStringSlice(ProtectedBranchPatternsKey)
 
 	for _, pattern := range patterns {
 		ProtectedBranchPatterns.AddPattern(pattern)
 	}
+
+	return nil
+}
+
+func genBranchTemplates() error {
+	templates := viper.GetStringMapString(BranchTemplatesKey)
+
+	for branchType, template := range templates {
+		BranchTemplates[branchType] = template
+	}
+
+	return nil
+}
+
+func genMergeStrategies() error {
+	strategies := viper.GetStringMapString(MergeStrategiesKey)
+
+	for branchName, strategy := range strategies {
+		MergeStrategies[branchName] = strategy
+	}
+
+	return nil
+}
+
+// genMergePolicies fails on policies it cannot read instead of leaving them
+// out, as a left out policy would not restrict merges at all.
+func genMergePolicies() error {
+	policies := make(map[string]MergePolicy)
+
+	if err := viper.UnmarshalKey(MergePoliciesKey, &policies); err != nil {
+		return fmt.Errorf("invalid %s: %w", MergePoliciesKey, err)
+	}
+
+	for branchName, policy := range policies {
+		for _, pattern := range append([]string{branchName}, policy.Sources...) {
+			if err := checkBranchPattern(pattern); err != nil {
+				return fmt.Errorf("invalid %s.%s: %w", MergePoliciesKey, branchName, err)
+			}
+		}
+
+		MergePolicies[branchName] = policy
+	}
+
+	return nil
+}
+
+// invalidBranchPatternChars are the characters git does not allow in branch
+// names. * is allowed in the patterns as the wildcard.
+const invalidBranchPatternChars = " ~^:?[\\"
+
+// checkBranchPattern returns an error if the glob-like pattern cannot match
+// any branch name.
+func checkBranchPattern(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return errors.New("empty branch pattern")
+	}
+
+	if strings.ContainsAny(pattern, invalidBranchPatternChars) || strings.Contains(pattern, "..") {
+		return fmt.Errorf("branch pattern %q contains characters not allowed in branch names", pattern)
+	}
+
+	_, err := branchGlob(pattern)
+
+	return err
 }
 
 var regexReplaceCharMap = []string{
@@ -104,6 +357,12 @@ func Load() error {
 func setDefaults() {
 	viper.SetDefault(AllowedBranchPatternsKey, make([]string, 0))
 	viper.SetDefault(ProtectedBranchPatternsKey, make([]string, 0))
+	viper.SetDefault(BranchTemplatesKey, make(map[string]string))
+	viper.SetDefault(AutoSetUpstreamKey, false)
+	viper.SetDefault(ReleasePatternKey, DefaultReleasePattern)
+	viper.SetDefault(MergeConflictStyleKey, ConflictStyleMerge)
+	viper.SetDefault(MergeStrategiesKey, make(map[string]string))
+	viper.SetDefault(MergePoliciesKey, make(map[string]interface{}))
 }
 
 func loadConfig() error {
@@ -120,13 +379,17 @@ func loadConfig() error {
 		setDefaults()
 
 		// Read in the config
-		err := viper.ReadInConfig()
+		if err := viper.ReadInConfig(); err != nil {
+			return err
+		}
 
 		for _, fn := range initFns {
-			fn()
+			if err := fn(); err != nil {
+				return err
+			}
 		}
 
-		return err
+		return nil
 	}
 
 	return err
@@ -10,6 +10,7 @@ require (
 	github.com/spf13/viper v1.10.1 // indirect
 	github.com/ugorji/go v1.1.4 // indirect
 	github.com/xordataexchange/crypt v0.0.3-0.20170626215501-b2862e3d0a77 // indirect
+	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1
 )
 
 replace github.com/libgit2/git2go/v31 => ./vendor/git2go
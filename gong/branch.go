@@ -1,23 +1,86 @@
 package gong
 
-import git "github.com/libgit2/git2go/v31"
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/erikjuhani/git-gong/config"
+	git "github.com/libgit2/git2go/v31"
+)
+
+// DefaultBranchTemplate is used to generate branch names for branch types
+// that do not have a template configured.
+const DefaultBranchTemplate = "{type}/{ticket}-{description}"
+
+var (
+	slugInvalidChars   = regexp.MustCompile(`[^a-z0-9]+`)
+	ticketInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	repeatedSeparators = regexp.MustCompile(`-{2,}`)
+)
 
 type Branch struct {
 	ReferenceID *git.Oid
 	RefName     string
 	Name        string
 	Shorthand   string
-	essence     *git.Branch
+	// Upstream is the shorthand name of the upstream branch e.g. origin/main.
+	// Upstream is empty if the branch does not track an upstream branch.
+	Upstream string
+	essence  *git.Branch
 }
 
 func NewBranch(branchName string, gitBranch *git.Branch) *Branch {
-	return &Branch{
+	branch := &Branch{
 		ReferenceID: gitBranch.Target(),
 		RefName:     gitBranch.Reference.Name(),
 		Name:        branchName,
 		Shorthand:   gitBranch.Shorthand(),
 		essence:     gitBranch,
 	}
+
+	if gitBranch.IsBranch() {
+		branch.Upstream = upstreamName(gitBranch)
+	}
+
+	return branch
+}
+
+// upstreamName returns the shorthand name of the upstream of a local branch,
+// or an empty string if the branch does not track an upstream branch.
+func upstreamName(gitBranch *git.Branch) string {
+	upstream, err := gitBranch.Upstream()
+	if err != nil {
+		return ""
+	}
+	defer Free(upstream)
+
+	return upstream.Shorthand()
+}
+
+// AheadBehind returns how many commits the branch is ahead and behind of its
+// upstream branch. Branches without an upstream are neither ahead nor behind.
+func (branch *Branch) AheadBehind() (int, int, error) {
+	if !branch.HasUpstream() {
+		return 0, 0, nil
+	}
+
+	upstream, err := branch.Essence().Upstream()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer Free(upstream)
+
+	owner := branch.Essence().Owner()
+
+	return owner.AheadBehind(branch.ReferenceID, upstream.Target())
+}
+
+// HasUpstream reports whether the branch tracks an upstream branch.
+func (branch *Branch) HasUpstream() bool {
+	return branch.Upstream != ""
 }
 
 func (branch *Branch) AnnotatedCommit() (*git.AnnotatedCommit, error) {
@@ -34,3 +97,92 @@ func (branch *Branch) Essence() *git.Branch {
 func (branch *Branch) Free() {
 	branch.Essence().Free()
 }
+
+// BranchNameFromTemplate generates a branch name for the given branch type
+// from the configured branch templates. Placeholders {type}, {ticket} and
+// {description} are replaced with the given values, description is slugified.
+// DefaultBranchTemplate is used only when no templates have been configured,
+// otherwise a branch type without a template is rejected.
+func BranchNameFromTemplate(branchType string, ticket string, description string) (string, error) {
+	if checkEmptyString(branchType) {
+		return "", errors.New("branch type is required")
+	}
+
+	template, ok := config.BranchTemplates[branchType]
+	if !ok {
+		if len(config.BranchTemplates) > 0 {
+			return "", errUnknownBranchType(branchType)
+		}
+
+		template = DefaultBranchTemplate
+	}
+
+	replacer := strings.NewReplacer(
+		"{type}", branchType,
+		"{ticket}", ticketInvalidChars.ReplaceAllString(strings.TrimSpace(ticket), "-"),
+		"{description}", slugify(description),
+	)
+
+	name := replacer.Replace(template)
+
+	// Clean up separators left behind by empty placeholders.
+	name = repeatedSeparators.ReplaceAllString(name, "-")
+	name = strings.ReplaceAll(name, "/-", "/")
+	name = strings.Trim(name, "-/")
+
+	if !git.ReferenceIsValidName(headRef + name) {
+		return "", fmt.Errorf("generated branch name %s is not a valid branch name", name)
+	}
+
+	if !config.AllowedBranchPatterns.Match(name) {
+		return "", errBranchNameNotAllowed(name)
+	}
+
+	return name, nil
+}
+
+func slugify(str string) string {
+	return strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(str), "-"), "-")
+}
+
+// errUnknownBranchType lists the branch types that have a template.
+func errUnknownBranchType(branchType string) error {
+	return fmt.Errorf(
+		"unknown branch type %s, use one of %s",
+		branchType, strings.Join(branchTypes(), ", "),
+	)
+}
+
+// branchTypes returns the configured branch types in sorted order.
+func branchTypes() []string {
+	var types []string
+	for branchType := range config.BranchTemplates {
+		types = append(types, branchType)
+	}
+	sort.Strings(types)
+
+	return types
+}
+
+// errBranchNameNotAllowed lists the allowed branch patterns and templates
+// alongside ErrBranchNameNotAllowed.
+func errBranchNameNotAllowed(branchName string) error {
+	sb := strings.Builder{}
+
+	patterns := config.GetStringSlice(config.AllowedBranchPatternsKey)
+	if len(patterns) > 0 {
+		sb.WriteString(fmt.Sprintf("\n  allowed patterns: %s", strings.Join(patterns, ", ")))
+	}
+
+	types := branchTypes()
+
+	if len(types) > 0 {
+		sb.WriteString("\n  allowed templates:")
+	}
+
+	for _, branchType := range types {
+		sb.WriteString(fmt.Sprintf("\n    --type %s: %s", branchType, config.BranchTemplates[branchType]))
+	}
+
+	return fmt.Errorf("%w: %s%s", ErrBranchNameNotAllowed, branchName, sb.String())
+}
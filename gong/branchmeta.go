@@ -0,0 +1,235 @@
+package gong
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+// metaRef holds the branch metadata as a commit history, where each branch
+// has a single file in the tree. The reference can be shared with a push of
+// refs/gong/meta.
+const metaRef = "refs/gong/meta"
+
+// BranchMeta is the description, owner and the linked ticket of a branch.
+type BranchMeta struct {
+	Description string
+	Owner       string
+	Ticket      string
+}
+
+// Empty reports whether the branch metadata has no values.
+func (meta *BranchMeta) Empty() bool {
+	return checkEmptyString(meta.Description) && checkEmptyString(meta.Owner) && checkEmptyString(meta.Ticket)
+}
+
+// String formats the metadata as owner and ticket header lines followed by
+// an empty line and the description. The empty line is always written so that
+// a description is never read back as a header.
+func (meta *BranchMeta) String() string {
+	sb := strings.Builder{}
+
+	if !checkEmptyString(meta.Owner) {
+		sb.WriteString(fmt.Sprintf("owner %s\n", meta.Owner))
+	}
+
+	if !checkEmptyString(meta.Ticket) {
+		sb.WriteString(fmt.Sprintf("ticket %s\n", meta.Ticket))
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%s\n", strings.TrimSpace(meta.Description)))
+
+	return sb.String()
+}
+
+// parseBranchMeta parses the metadata formatted by String. Header lines are
+// read only while they are owner or ticket lines, the rest after the empty
+// separator line is the description.
+func parseBranchMeta(content string) *BranchMeta {
+	meta := &BranchMeta{}
+
+	lines := strings.Split(content, "\n")
+
+	i := 0
+	for ; i < len(lines); i++ {
+		fields := strings.SplitN(lines[i], " ", 2)
+		if len(fields) != 2 {
+			break
+		}
+
+		if fields[0] == "owner" {
+			meta.Owner = fields[1]
+		} else if fields[0] == "ticket" {
+			meta.Ticket = fields[1]
+		} else {
+			break
+		}
+	}
+
+	if i < len(lines) && lines[i] == "" {
+		i++
+	}
+
+	meta.Description = strings.TrimSpace(strings.Join(lines[i:], "\n"))
+
+	return meta
+}
+
+// metaEntryName escapes the branch name into a single tree entry name.
+func metaEntryName(branchName string) string {
+	return url.QueryEscape(branchName)
+}
+
+// BranchMeta returns the metadata of the branch. If the branch has no
+// metadata an empty BranchMeta is returned.
+func (repo *Repository) BranchMeta(branchName string) (*BranchMeta, error) {
+	tree, err := repo.metaTree()
+	if err != nil || tree == nil {
+		return &BranchMeta{}, err
+	}
+	defer Free(tree)
+
+	entry := tree.EntryByName(metaEntryName(branchName))
+	if entry == nil {
+		return &BranchMeta{}, nil
+	}
+
+	blob, err := repo.Essence().LookupBlob(entry.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(blob)
+
+	return parseBranchMeta(string(blob.Contents())), nil
+}
+
+// SetBranchMeta stores the metadata of the branch. An empty metadata removes
+// the metadata of the branch.
+func (repo *Repository) SetBranchMeta(branchName string, meta *BranchMeta) error {
+	if meta.Empty() {
+		existing, err := repo.BranchMeta(branchName)
+		if err != nil || existing.Empty() {
+			return err
+		}
+	}
+
+	message := fmt.Sprintf("gong: describe branch %s", branchName)
+
+	return repo.updateMeta(message, func(builder *git.TreeBuilder) error {
+		name := metaEntryName(branchName)
+
+		if meta.Empty() {
+			return builder.Remove(name)
+		}
+
+		blobID, err := repo.Essence().CreateBlobFromBuffer([]byte(meta.String()))
+		if err != nil {
+			return err
+		}
+
+		return builder.Insert(name, blobID, git.FilemodeBlob)
+	})
+}
+
+// moveBranchMeta moves the metadata of a renamed branch to the new name.
+func (repo *Repository) moveBranchMeta(oldName string, newName string) error {
+	tree, err := repo.metaTree()
+	if err != nil || tree == nil {
+		return err
+	}
+	defer Free(tree)
+
+	entry := tree.EntryByName(metaEntryName(oldName))
+	if entry == nil {
+		return nil
+	}
+
+	message := fmt.Sprintf("gong: rename branch %s to %s", oldName, newName)
+
+	return repo.updateMeta(message, func(builder *git.TreeBuilder) error {
+		if err := builder.Remove(metaEntryName(oldName)); err != nil {
+			return err
+		}
+
+		return builder.Insert(metaEntryName(newName), entry.Id, git.FilemodeBlob)
+	})
+}
+
+// metaTree returns the tree of the metadata reference or nil if the
+// metadata reference does not exist yet.
+func (repo *Repository) metaTree() (*git.Tree, error) {
+	ref, err := repo.Essence().References.Lookup(metaRef)
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrorCodeNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer Free(ref)
+
+	commit, err := repo.FindCommit(ref.Target())
+	if err != nil {
+		return nil, err
+	}
+	defer Free(commit)
+
+	return commit.Tree()
+}
+
+// updateMeta records a new metadata commit with the tree built by update.
+func (repo *Repository) updateMeta(message string, update func(builder *git.TreeBuilder) error) error {
+	var parents []*git.Commit
+
+	builder, err := repo.Essence().TreeBuilder()
+	if err != nil {
+		return err
+	}
+	defer Free(builder)
+
+	if ref, err := repo.Essence().References.Lookup(metaRef); err == nil {
+		defer Free(ref)
+
+		parent, err := repo.FindCommit(ref.Target())
+		if err != nil {
+			return err
+		}
+		defer Free(parent)
+
+		tree, err := parent.Tree()
+		if err != nil {
+			return err
+		}
+		defer Free(tree)
+
+		// Start from the previous metadata tree.
+		for i := uint64(0); i < tree.EntryCount(); i++ {
+			entry := tree.EntryByIndex(i)
+			if err := builder.Insert(entry.Name, entry.Id, entry.Filemode); err != nil {
+				return err
+			}
+		}
+
+		parents = append(parents, parent.Essence())
+	}
+
+	if err := update(builder); err != nil {
+		return err
+	}
+
+	treeID, err := builder.Write()
+	if err != nil {
+		return err
+	}
+
+	tree, err := repo.FindTree(treeID)
+	if err != nil {
+		return err
+	}
+	defer Free(tree)
+
+	_, err = repo.Essence().CreateCommit(metaRef, signature(), signature(), message, tree, parents...)
+
+	return err
+}
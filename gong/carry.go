@@ -0,0 +1,172 @@
+package gong
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+// CarryBranch switches to the branch and carries the uncommitted changes,
+// including untracked files, onto it with a three-way merge. If the changes
+// conflict with the branch nothing is changed and ErrCarryConflict is
+// returned.
+func (repo *Repository) CarryBranch(branchName string) (*Branch, error) {
+	if err := repo.checkSequenceAllowed(); err != nil {
+		return nil, err
+	}
+
+	changed, err := repo.Changed()
+	if err != nil {
+		return nil, err
+	}
+
+	if !changed {
+		return repo.CheckoutBranch(branchName)
+	}
+
+	currentBranch, err := repo.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(currentBranch)
+
+	branch, err := repo.FindBranch(branchName, git.BranchLocal)
+	created := err != nil
+
+	if created {
+		branch, err = repo.createCheckoutBranch(branchName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer Free(branch)
+
+	if repo.Stashes.Has(branch) {
+		return nil, fmt.Errorf("branch %s has stashed changes, switch without --carry or drop the stash first", branchName)
+	}
+
+	stash, err := repo.Stashes.Carry(branchName)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep binds the changes that could not be popped to the branch they are
+	// on, to be popped or dropped like any stash of the branch.
+	keep := func(onBranch string, cause error, err error) error {
+		if keepErr := repo.Stashes.keepCarried(stash, onBranch); keepErr != nil {
+			return fmt.Errorf("%v, the changes are kept in stash %s: %w", cause, stash.ID, err)
+		}
+
+		return fmt.Errorf("%v, the changes are kept in the stash of branch %s: %w", cause, onBranch, err)
+	}
+
+	// restore puts the changes back to the original branch and removes the
+	// branch if it was created only for carrying.
+	restore := func(cause error) error {
+		if err := repo.Stashes.PopStash(stash); err != nil {
+			return keep(currentBranch.Name, cause, err)
+		}
+
+		if created {
+			if err := branch.Essence().Delete(); err != nil {
+				return fmt.Errorf("%v: %w", cause, err)
+			}
+		}
+
+		return cause
+	}
+
+	conflicts, err := repo.carryConflicts(stash, branch)
+	if err != nil {
+		return nil, restore(err)
+	}
+
+	if len(conflicts) > 0 {
+		return nil, restore(fmt.Errorf("%w\n  %s", ErrCarryConflict, strings.Join(conflicts, "\n  ")))
+	}
+
+	switched, err := repo.CheckoutBranch(branchName)
+	if err != nil {
+		return nil, restore(err)
+	}
+
+	if err := repo.Stashes.PopStash(stash); err != nil {
+		Free(switched)
+		return nil, keep(branchName, errors.New("could not carry the changes"), err)
+	}
+
+	return switched, nil
+}
+
+// carryConflicts merges the stashed changes with the branch in memory and
+// returns the conflicting paths. Untracked files conflict when the branch
+// has a file in the same path.
+func (repo *Repository) carryConflicts(stash *Stash, branch *Branch) ([]string, error) {
+	stashCommit, err := repo.Essence().LookupCommit(stash.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(stashCommit)
+
+	baseTree, err := stashCommit.Parent(0).Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(baseTree)
+
+	changesTree, err := stashCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(changesTree)
+
+	branchCommit, err := repo.FindCommit(branch.ReferenceID)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(branchCommit)
+
+	branchTree, err := branchCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(branchTree)
+
+	index, err := repo.Essence().MergeTrees(baseTree, branchTree, changesTree, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(index)
+
+	conflicts, err := conflictedPaths(index)
+	if err != nil {
+		return nil, err
+	}
+
+	// The untracked files are stored in the third parent of the stash.
+	if stashCommit.ParentCount() < 3 {
+		return conflicts, nil
+	}
+
+	untrackedTree, err := stashCommit.Parent(2).Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(untrackedTree)
+
+	err = untrackedTree.Walk(func(root string, entry *git.TreeEntry) int {
+		if entry.Type != git.ObjectBlob {
+			return 0
+		}
+
+		if branchEntry, err := branchTree.EntryByPath(root + entry.Name); err == nil && branchEntry != nil {
+			conflicts = append(conflicts, root+entry.Name)
+		}
+
+		return 0
+	})
+
+	return conflicts, err
+}
@@ -0,0 +1,104 @@
+package gong
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+var conflictMarker = []byte("<<<<<<<")
+
+// Conflicts returns the paths that have unresolved conflicts in the index.
+func (repo *Repository) Conflicts() ([]string, error) {
+	index, err := repo.Essence().Index()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(index)
+
+	return conflictedPaths(index)
+}
+
+func conflictedPaths(index *git.Index) ([]string, error) {
+	if !index.HasConflicts() {
+		return nil, nil
+	}
+
+	iter, err := index.ConflictIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(iter)
+
+	unique := make(map[string]struct{})
+
+	for {
+		conflict, err := iter.Next()
+		if err != nil {
+			if git.IsErrorCode(err, git.ErrorCodeIterOver) {
+				break
+			}
+			return nil, err
+		}
+
+		for _, entry := range []*git.IndexEntry{conflict.Our, conflict.Their, conflict.Ancestor} {
+			if entry != nil {
+				unique[entry.Path] = struct{}{}
+				break
+			}
+		}
+	}
+
+	var paths []string
+	for path := range unique {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// resolveConflicts marks the conflicts resolved by staging the working tree
+// version of each conflicted path. Resolution fails if a conflicted file
+// still contains conflict markers.
+func (repo *Repository) resolveConflicts() error {
+	index, err := repo.Essence().Index()
+	if err != nil {
+		return err
+	}
+	defer Free(index)
+
+	paths, err := conflictedPaths(index)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(filepath.Join(repo.Path, path))
+		if err != nil {
+			// The file was deleted as a resolution.
+			if err := index.RemoveConflict(path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if bytes.Contains(content, conflictMarker) {
+			return fmt.Errorf("%s still contains conflict markers, resolve the conflicts first", path)
+		}
+
+		if err := index.RemoveConflict(path); err != nil {
+			return err
+		}
+
+		if err := index.AddByPath(path); err != nil {
+			return err
+		}
+	}
+
+	return index.Write()
+}
@@ -39,7 +39,7 @@ func parseArgs(args []string) error {
 	case "add":
 		fallthrough
 	case "commit":
-		if config.ProtectedBranchPatterns.Match(branch.Name) {
+		if config.IsProtectedBranch(branch.Name) {
 			return errors.New("trying to commit on a protected branch, operation aborted")
 		}
 		return nil
@@ -49,7 +49,7 @@ func parseArgs(args []string) error {
 		}
 
 		if !config.AllowedBranchPatterns.Match(args[1]) {
-			return errors.New("error branch name did not match allowed template patterns")
+			return errBranchNameNotAllowed(args[1])
 		}
 
 		return nil
@@ -59,7 +59,7 @@ func parseArgs(args []string) error {
 		}
 
 		if !config.AllowedBranchPatterns.Match(args[2]) {
-			return errors.New("error branch name did not match allowed template patterns")
+			return errBranchNameNotAllowed(args[2])
 		}
 		return nil
 	}
@@ -67,6 +67,40 @@ func parseArgs(args []string) error {
 	return nil
 }
 
+// setUpstreamArgs sets the upstream on the first push of a branch when
+// push.auto_set_upstream is enabled, by turning a plain push into
+// push --set-upstream <remote> <branchname>. The args are returned unchanged
+// when the branch or the remote cannot be determined, e.g. on a detached HEAD
+// or with no remotes, to let git report the error.
+func setUpstreamArgs(args []string) []string {
+	if len(args) != 1 || args[0] != "push" || !config.GetBool(config.AutoSetUpstreamKey) {
+		return args
+	}
+
+	repo, err := Open()
+	if err != nil {
+		return args
+	}
+	defer Free(repo)
+
+	branch, err := repo.Head.Branch()
+	if err != nil {
+		return args
+	}
+	defer Free(branch)
+
+	if branch.HasUpstream() {
+		return args
+	}
+
+	remote, err := repo.DefaultRemote()
+	if err != nil {
+		return args
+	}
+
+	return []string{"push", "--set-upstream", remote, branch.Name}
+}
+
 func RunGitCommand(args []string) error {
 	if !commandExists("git") {
 		return errors.New("git executable not found in path")
@@ -76,7 +110,7 @@ func RunGitCommand(args []string) error {
 		return err
 	}
 
-	git := exec.Command("git", args...)
+	git := exec.Command("git", setUpstreamArgs(args)...)
 	git.Stdin = os.Stdin
 	git.Stdout = os.Stdout
 	git.Stderr = os.Stderr
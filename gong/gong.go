@@ -9,7 +9,28 @@ import (
 )
 
 var (
-	ErrNothingToCommit = errors.New("nothing to commit")
+	ErrNothingToCommit         = errors.New("nothing to commit")
+	ErrBranchNameNotAllowed    = errors.New("error branch name did not match allowed template patterns")
+	ErrStashConflict           = errors.New("stash conflicts with the working tree, conflict markers were left in place")
+	ErrSwitchConflict          = errors.New("switch stopped, the stash of the branch conflicts. Resolve the conflicts and run gong switch --continue or gong switch --abort")
+	ErrAmbiguousRemoteBranch   = errors.New("branch name is ambiguous across remotes")
+	ErrSwitchInProgress        = errors.New("switch in progress, run gong switch --continue or gong switch --abort first")
+	ErrNoSwitchInProgress      = errors.New("no switch in progress")
+	ErrCarryConflict           = errors.New("changes conflict with the branch, nothing was carried")
+	ErrMergeConflict           = errors.New("merge stopped, conflict markers were left in place. Resolve the conflicts and run gong merge --continue or gong merge --abort")
+	ErrMergeInProgress         = errors.New("merge in progress, run gong merge --continue or gong merge --abort first")
+	ErrNoMergeInProgress       = errors.New("no merge in progress")
+	ErrMergeUncommittedChanges = errors.New("uncommitted changes in tracked files would be lost if the merge was aborted, commit or stash the changes first")
+	ErrRebaseConflict          = errors.New("rebase stopped, conflict markers were left in place. Resolve the conflicts and run gong rebase --continue, gong rebase --skip or gong rebase --abort")
+	ErrRebaseInProgress        = errors.New("rebase in progress, run gong rebase --continue, gong rebase --skip or gong rebase --abort first")
+	ErrNoRebaseInProgress      = errors.New("no rebase in progress")
+	ErrPickConflict            = errors.New("pick stopped, conflict markers were left in place. Resolve the conflicts and run gong pick --continue or gong pick --abort")
+	ErrPickInProgress          = errors.New("pick in progress, run gong pick --continue or gong pick --abort first")
+	ErrNoPickInProgress        = errors.New("no pick in progress")
+	ErrRevertConflict          = errors.New("revert stopped, conflict markers were left in place. Resolve the conflicts and run gong revert --continue or gong revert --abort")
+	ErrRevertInProgress        = errors.New("revert in progress, run gong revert --continue or gong revert --abort first")
+	ErrNoRevertInProgress      = errors.New("no revert in progress")
+	ErrMergePolicy             = errors.New("merge refused by the merge policy of the branch")
 )
 
 var (
@@ -17,7 +38,8 @@ var (
 )
 
 const (
-	headRef = "refs/heads/"
+	headRef          = "refs/heads/"
+	defaultBranchKey = "gong.defaultBranch"
 )
 
 func TestRepo() (*testRepository, func(), error) {
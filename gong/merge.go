@@ -0,0 +1,484 @@
+package gong
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/erikjuhani/git-gong/config"
+	git "github.com/libgit2/git2go/v31"
+)
+
+const (
+	mergeHeadFile = "MERGE_HEAD"
+	mergeMsgFile  = "MERGE_MSG"
+)
+
+// conflictStyle returns the checkout strategy for the configured conflict
+// marker style. libgit2 does not support zdiff3, so it is rejected instead of
+// silently writing diff3 markers.
+func conflictStyle() (git.CheckoutStrategy, error) {
+	switch style := config.GetString(config.MergeConflictStyleKey); style {
+	case "", config.ConflictStyleMerge:
+		return git.CheckoutConflictStyleMerge, nil
+	case config.ConflictStyleDiff3:
+		return git.CheckoutConflictStyleDiff3, nil
+	case config.ConflictStyleZDiff3:
+		return 0, fmt.Errorf("%s %s is not supported, use %s or %s", config.MergeConflictStyleKey, style, config.ConflictStyleMerge, config.ConflictStyleDiff3)
+	default:
+		return 0, fmt.Errorf("unknown %s %s, use %s or %s", config.MergeConflictStyleKey, style, config.ConflictStyleMerge, config.ConflictStyleDiff3)
+	}
+}
+
+// MergeStrategy decides how a branch is merged into the current branch.
+type MergeStrategy string
+
+const (
+	// MergeFastForward fast-forwards when the current branch has not diverged
+	// and otherwise creates a merge commit.
+	MergeFastForward MergeStrategy = "ff"
+	// MergeFastForwardOnly only fast-forwards and refuses diverged branches.
+	MergeFastForwardOnly MergeStrategy = "ff-only"
+	// MergeNoFastForward always creates a merge commit.
+	MergeNoFastForward MergeStrategy = "no-ff"
+	// MergeSquash squashes the changes of the branch into a single commit
+	// without recording the branch as a parent.
+	MergeSquash MergeStrategy = "squash"
+)
+
+// mergeState records the strategy of a merge stopped by conflicts.
+const mergeState = "MERGE"
+
+var mergeStrategies = []MergeStrategy{MergeFastForward, MergeFastForwardOnly, MergeNoFastForward, MergeSquash}
+
+// ParseMergeStrategy parses a merge strategy name.
+func ParseMergeStrategy(name string) (MergeStrategy, error) {
+	for _, strategy := range mergeStrategies {
+		if string(strategy) == name {
+			return strategy, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown merge strategy %s, use one of ff, ff-only, no-ff or squash", name)
+}
+
+// DefaultMergeStrategy returns the merge strategy configured for merges into
+// the branch, or MergeFastForward if none has been configured. If the merge
+// policy of the branch does not allow the strategy, the first strategy the
+// policy allows is returned instead.
+func DefaultMergeStrategy(branchName string) (MergeStrategy, error) {
+	name := config.MergeStrategyFor(branchName)
+	if checkEmptyString(name) {
+		name = string(MergeFastForward)
+	}
+
+	if policy, ok := config.MergePolicyFor(branchName); ok && !policy.AllowsStrategy(name) {
+		strategy, err := ParseMergeStrategy(policy.Strategies[0])
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", config.MergePoliciesKey, err)
+		}
+
+		return strategy, nil
+	}
+
+	strategy, err := ParseMergeStrategy(name)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", config.MergeStrategiesKey, err)
+	}
+
+	return strategy, nil
+}
+
+// MergeInProgress reports whether a merge stopped by conflicts is waiting to
+// be continued or aborted.
+func (repo *Repository) MergeInProgress() bool {
+	return repo.Essence().State() == git.RepositoryStateMerge
+}
+
+// ResolveMergeSource resolves the source of a merge. The source can be a local
+// branch, a remote branch, a tag, a commit or any other revision, or a release
+// query such as latest or ^1.2. A bare branch name that only exists on a
+// remote resolves to the remote branch.
+func (repo *Repository) ResolveMergeSource(source string) (*Revision, error) {
+	revision, err := repo.ResolveRevision(source)
+	if err == nil || !errors.Is(err, ErrRevisionNotFound) {
+		return revision, err
+	}
+
+	remoteBranches, err := repo.RemoteBranches(source)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(remoteBranches) {
+	case 0:
+		return nil, fmt.Errorf("%w, no branch, remote branch, release, tag or commit found by %s", ErrRevisionNotFound, source)
+	case 1:
+		return repo.ResolveRevision(remoteRef + remoteBranches[0])
+	default:
+		return nil, fmt.Errorf(
+			"%w: %s exists on %s, merge one of them instead",
+			ErrAmbiguousRemoteBranch, source, strings.Join(remoteBranches, ", "),
+		)
+	}
+}
+
+// annotatedCommit returns the annotated commit of the revision, annotated with
+// the reference it was resolved from if any.
+func (repo *Repository) annotatedCommit(revision *Revision) (*git.AnnotatedCommit, error) {
+	if checkEmptyString(revision.RefName) {
+		return repo.Essence().LookupAnnotatedCommit(revision.Commit.ID)
+	}
+
+	ref, err := repo.Essence().References.Lookup(revision.RefName)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(ref)
+
+	return repo.Essence().AnnotatedCommitFromRef(ref)
+}
+
+// mergeSourceName describes the merge source in merge messages, e.g. branch
+// feature, tag v1.2.0 or commit 1a2b3c4.
+func mergeSourceName(revision *Revision) string {
+	if revision.Kind == RevisionCommit {
+		return fmt.Sprintf("%s %s", revision.Kind, revision.Commit.ID.String()[:7])
+	}
+
+	return revision.String()
+}
+
+// Merge merges the source to the current branch with the strategy. The source
+// is resolved with ResolveMergeSource. An empty strategy uses the default
+// strategy configured for the current branch. If the changes conflict the
+// merge stops and leaves the conflict markers in the working tree, the merge
+// is then finished with ContinueMerge or undone with AbortMerge.
+func (repo *Repository) Merge(source string, strategy MergeStrategy) error {
+	if err := repo.checkSequenceAllowed(); err != nil {
+		return err
+	}
+
+	style, err := conflictStyle()
+	if err != nil {
+		return err
+	}
+
+	destinationbranch, err := repo.Head.Branch()
+	if err != nil {
+		return err
+	}
+	defer Free(destinationbranch)
+
+	if strategy == "" {
+		strategy, err = DefaultMergeStrategy(destinationbranch.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	revision, err := repo.ResolveMergeSource(source)
+	if err != nil {
+		return err
+	}
+	defer Free(revision)
+
+	sourceName := mergeSourceName(revision)
+
+	policy, err := checkMergePolicy(destinationbranch.Name, revision, strategy)
+	if err != nil {
+		return err
+	}
+
+	theirAnnCommit, err := repo.annotatedCommit(revision)
+	if err != nil {
+		return err
+	}
+	defer Free(theirAnnCommit)
+
+	mergeHeads := make([]*git.AnnotatedCommit, 1)
+	mergeHeads[0] = theirAnnCommit
+
+	analysis, _, err := repo.essence.MergeAnalysis(mergeHeads)
+	if err != nil {
+		return err
+	}
+
+	fastForward := analysis&git.MergeAnalysisFastForward != 0
+
+	switch {
+	case analysis&git.MergeAnalysisUnborn != 0:
+		// Head is unborn, merge is impossible
+		return errors.New("cannot merge head doest not exist")
+	case analysis&git.MergeAnalysisUpToDate != 0:
+		// Nothing to merge
+		return errors.New("merge failed, nothing to merge")
+	case !fastForward && strategy == MergeFastForwardOnly:
+		return fmt.Errorf(
+			"cannot fast-forward, %s has diverged from %s. Rebase onto %s or merge with another strategy",
+			destinationbranch.Name, sourceName, revision.Name,
+		)
+	}
+
+	fastForwarded := fastForward && (strategy == MergeFastForward || strategy == MergeFastForwardOnly)
+
+	// AbortMerge resets the working tree to head, so a merge commit on top of
+	// uncommitted changes could lose them. A fast-forward keeps them.
+	changed, err := repo.trackedChanges()
+	if err != nil {
+		return err
+	}
+
+	if changed && !fastForwarded {
+		return ErrMergeUncommittedChanges
+	}
+
+	if err := repo.runStatusCommand(policy); err != nil {
+		return err
+	}
+
+	if fastForwarded {
+		// History has not diverted so we fast forward and just add the commits on top
+		return repo.fastForward(destinationbranch, revision)
+	}
+
+	mergeOpts, err := git.DefaultMergeOptions()
+	if err != nil {
+		return err
+	}
+
+	mergeOpts.FileFavor = git.MergeFileFavorNormal
+
+	checkoutOpts := git.CheckoutOpts{
+		Strategy: git.CheckoutSafe | git.CheckoutRecreateMissing | git.CheckoutAllowConflicts | style,
+	}
+
+	if err := repo.Essence().Merge(mergeHeads, &mergeOpts, &checkoutOpts); err != nil {
+		return err
+	}
+
+	mergeMessage := fmt.Sprintf("Merge %s into %s", sourceName, destinationbranch.Name)
+
+	if strategy == MergeSquash {
+		mergeMessage, err = repo.squashMessage(destinationbranch, revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	conflicts, err := repo.Conflicts()
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) > 0 {
+		if err := ioutil.WriteFile(filepath.Join(repo.GitPath, mergeMsgFile), []byte(mergeMessage+"\n"), 0644); err != nil {
+			return err
+		}
+
+		if err := repo.writeState(mergeState, map[string]string{"strategy": string(strategy)}); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("%w\n  %s", ErrMergeConflict, strings.Join(conflicts, "\n  "))
+	}
+
+	mergeCommit, err := repo.commitMerge(mergeMessage, strategy == MergeSquash)
+	if err != nil {
+		return err
+	}
+	defer Free(mergeCommit)
+
+	return nil
+}
+
+// fastForward moves the current branch to the commit of the source.
+func (repo *Repository) fastForward(destinationBranch *Branch, source *Revision) error {
+	checkoutOpts := git.CheckoutOpts{
+		Strategy: git.CheckoutSafe | git.CheckoutRecreateMissing | git.CheckoutUseTheirs,
+	}
+
+	tree, err := repo.FindTree(source.Commit.Essence().TreeId())
+	if err != nil {
+		return err
+	}
+	defer Free(tree)
+
+	if err := repo.CheckoutTree(tree, &checkoutOpts); err != nil {
+		return err
+	}
+
+	ref, err := destinationBranch.Essence().SetTarget(
+		source.Commit.ID,
+		fmt.Sprintf("merge %s: fast-forward", source.Name),
+	)
+	if err != nil {
+		return err
+	}
+	defer Free(ref)
+
+	return nil
+}
+
+// squashMessage returns the message of a squash merge listing the summaries
+// of the squashed commits.
+func (repo *Repository) squashMessage(destinationBranch *Branch, source *Revision) (string, error) {
+	walk, err := repo.Essence().Walk()
+	if err != nil {
+		return "", err
+	}
+	defer Free(walk)
+
+	walk.Sorting(git.SortTopological | git.SortReverse)
+
+	if err := walk.Push(source.Commit.ID); err != nil {
+		return "", err
+	}
+
+	if err := walk.Hide(destinationBranch.ReferenceID); err != nil {
+		return "", err
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("Squash %s into %s\n", mergeSourceName(source), destinationBranch.Name))
+
+	err = walk.Iterate(func(commit *git.Commit) bool {
+		sb.WriteString(fmt.Sprintf("\n* %s", commit.Summary()))
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// ContinueMerge finishes a merge stopped by conflicts after the conflicts have
+// been resolved in the working tree, and returns the merge commit.
+func (repo *Repository) ContinueMerge() (*Commit, error) {
+	if !repo.MergeInProgress() {
+		return nil, ErrNoMergeInProgress
+	}
+
+	if err := repo.resolveConflicts(); err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(repo.GitPath, mergeMsgFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	state, err := repo.readState(mergeState)
+	if err != nil {
+		return nil, err
+	}
+
+	squash := state != nil && state["strategy"] == string(MergeSquash)
+
+	return repo.commitMerge(strings.TrimSpace(strings.Join(lines, "\n")), squash)
+}
+
+// AbortMerge stops a merge stopped by conflicts and restores the working tree
+// and the index to the state before the merge.
+func (repo *Repository) AbortMerge() error {
+	if !repo.MergeInProgress() {
+		return ErrNoMergeInProgress
+	}
+
+	head, err := repo.Head.Commit()
+	if err != nil {
+		return err
+	}
+	defer Free(head)
+
+	checkoutOpts := &git.CheckoutOpts{
+		Strategy: git.CheckoutForce,
+	}
+
+	if err := repo.Essence().ResetToCommit(head.Essence(), git.ResetHard, checkoutOpts); err != nil {
+		return err
+	}
+
+	if err := repo.removeState(mergeState); err != nil {
+		return err
+	}
+
+	return repo.Essence().StateCleanup()
+}
+
+// commitMerge commits the merged index with the merge heads as the other
+// parents and cleans up the merge state. A squash merge does not record the
+// merge heads as parents.
+func (repo *Repository) commitMerge(message string, squash bool) (*Commit, error) {
+	content, err := ioutil.ReadFile(filepath.Join(repo.GitPath, mergeHeadFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var mergeHeads []string
+	if !squash {
+		mergeHeads = strings.Fields(string(content))
+	}
+
+	var parents []*Commit
+	for _, line := range mergeHeads {
+		id, err := git.NewOid(line)
+		if err != nil {
+			return nil, err
+		}
+
+		commit, err := repo.FindCommit(id)
+		if err != nil {
+			return nil, err
+		}
+		defer Free(commit)
+
+		parents = append(parents, commit)
+	}
+
+	index, err := repo.Essence().Index()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(index)
+
+	treeID, err := index.WriteTree()
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := repo.FindTree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(tree)
+
+	// Head is added as the first parent by CreateCommit.
+	mergeCommit, err := repo.CreateCommit(tree, message, parents...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.removeState(mergeState); err != nil {
+		Free(mergeCommit)
+		return nil, err
+	}
+
+	if err := repo.Essence().StateCleanup(); err != nil {
+		Free(mergeCommit)
+		return nil, err
+	}
+
+	return mergeCommit, nil
+}
@@ -0,0 +1,80 @@
+package gong
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/erikjuhani/git-gong/config"
+)
+
+// checkMergePolicy refuses merging the source into the destination branch
+// with the strategy if the merge policy of the branch does not allow it, and
+// returns the policy. A branch without a policy allows any merge.
+func checkMergePolicy(destination string, source *Revision, strategy MergeStrategy) (config.MergePolicy, error) {
+	policy, ok := config.MergePolicyFor(destination)
+	if !ok {
+		return policy, nil
+	}
+
+	if len(policy.Sources) > 0 {
+		sourceName, ok := policySourceName(source)
+		if !ok || !policy.AllowsSource(sourceName) {
+			return policy, fmt.Errorf(
+				"%w, %s cannot be merged into %s. Allowed sources are the branches %s",
+				ErrMergePolicy, mergeSourceName(source), destination, strings.Join(policy.Sources, ", "),
+			)
+		}
+	}
+
+	if !policy.AllowsStrategy(string(strategy)) {
+		return policy, fmt.Errorf(
+			"%w, merges into %s cannot use the %s strategy. Allowed strategies are %s",
+			ErrMergePolicy, destination, strategy, strings.Join(policy.Strategies, ", "),
+		)
+	}
+
+	return policy, nil
+}
+
+// policySourceName returns the branch name the source patterns of a merge
+// policy are matched against. Remote branches are matched without the remote
+// name. Tags, releases and commits are not branches and false is returned, so
+// that a policy restricting the sources refuses them.
+func policySourceName(source *Revision) (string, bool) {
+	switch source.Kind {
+	case RevisionBranch:
+		return source.Name, true
+	case RevisionRemoteBranch:
+		if parts := strings.SplitN(source.Name, "/", 2); len(parts) == 2 {
+			return parts[1], true
+		}
+	}
+
+	return "", false
+}
+
+// runStatusCommand runs the status command of the merge policy in the root of
+// the working tree, and refuses the merge if the command fails.
+func (repo *Repository) runStatusCommand(policy config.MergePolicy) error {
+	if checkEmptyString(policy.StatusCommand) {
+		return nil
+	}
+
+	var output bytes.Buffer
+
+	command := exec.Command("sh", "-c", policy.StatusCommand)
+	command.Dir = repo.Path
+	command.Stdout = &output
+	command.Stderr = &output
+
+	if err := command.Run(); err != nil {
+		return fmt.Errorf(
+			"%w, status command %s failed: %v\n%s",
+			ErrMergePolicy, policy.StatusCommand, err, strings.TrimSpace(output.String()),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package gong
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/erikjuhani/git-gong/config"
+	git "github.com/libgit2/git2go/v31"
+)
+
+// pickState records a pick that stopped because a commit conflicted.
+const pickState = "PICK"
+
+// PickInProgress reports whether a stopped pick waits to be continued or
+// aborted.
+func (repo *Repository) PickInProgress() (bool, error) {
+	state, err := repo.readState(pickState)
+	return state != nil, err
+}
+
+// Pick applies the changes of the commits the revisions resolve to on top of
+// head as new commits, oldest first. A revision is either a single revision or
+// a range a..b of the commits reachable from b but not from a. Merge commits
+// are picked relative to the mainline parent, counting from 1. With
+// recordOrigin the picked commit is referred to in the message of the new
+// commit. If a commit conflicts the pick stops with the conflict markers in
+// the working tree to be continued or aborted. The number of created commits
+// is returned, leaving out the commits whose changes were already applied.
+func (repo *Repository) Pick(revisions []string, mainline uint, recordOrigin bool) (int, error) {
+	seq, err := repo.newSequence(pickState)
+	if err != nil {
+		return 0, err
+	}
+
+	if config.IsProtectedBranch(seq.State["branch"]) {
+		return 0, errors.New("trying to commit on a protected branch, operation aborted")
+	}
+
+	var steps []sequenceStep
+
+	for _, revision := range revisions {
+		revisionSteps, err := repo.pickSteps(revision)
+		if err != nil {
+			return 0, err
+		}
+
+		steps = append(steps, revisionSteps...)
+	}
+
+	if len(steps) == 0 {
+		return 0, errors.New("nothing to pick")
+	}
+
+	if err := repo.stashForSequence(seq); err != nil {
+		return 0, err
+	}
+
+	seq.State["mainline"] = strconv.FormatUint(uint64(mainline), 10)
+	seq.State["origin"] = strconv.FormatBool(recordOrigin)
+
+	head, err := repo.Head.Commit()
+	if err != nil {
+		return 0, err
+	}
+	defer Free(head)
+
+	if err := repo.runSequence(seq, head.ID, steps); err != nil {
+		return 0, err
+	}
+
+	return repo.createdCommits(head.ID)
+}
+
+// ContinuePick commits the conflicting commit of a stopped pick after the
+// conflicts have been resolved, and picks the rest of the commits.
+func (repo *Repository) ContinuePick() error {
+	seq, steps, err := repo.readSequence(pickState, ErrNoPickInProgress)
+	if err != nil {
+		return err
+	}
+
+	return repo.continueSequence(seq, steps)
+}
+
+// AbortPick stops a pick and returns head to the commit it was before the
+// pick, restoring the stashed changes.
+func (repo *Repository) AbortPick() error {
+	seq, _, err := repo.readSequence(pickState, ErrNoPickInProgress)
+	if err != nil {
+		return err
+	}
+
+	return repo.abortSequence(seq)
+}
+
+// pickSteps resolves the revision, or the range of revisions, to the commits
+// to pick, oldest first.
+func (repo *Repository) pickSteps(revision string) ([]sequenceStep, error) {
+	if !strings.Contains(revision, "..") || strings.Contains(revision, "...") {
+		resolved, err := repo.ResolveRevision(revision)
+		if err != nil {
+			return nil, err
+		}
+		defer Free(resolved)
+
+		return []sequenceStep{{Action: sequencePick, ID: resolved.Commit.ID}}, nil
+	}
+
+	bounds := strings.SplitN(revision, "..", 2)
+
+	var ids []*git.Oid
+
+	for _, bound := range bounds {
+		if checkEmptyString(bound) {
+			bound = headRefName
+		}
+
+		resolved, err := repo.ResolveRevision(bound)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, resolved.Commit.ID)
+		Free(resolved)
+	}
+
+	walk, err := repo.Essence().Walk()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(walk)
+
+	walk.Sorting(git.SortTopological | git.SortReverse)
+
+	if err := walk.Push(ids[1]); err != nil {
+		return nil, err
+	}
+
+	if err := walk.Hide(ids[0]); err != nil {
+		return nil, err
+	}
+
+	var steps []sequenceStep
+
+	err = walk.Iterate(func(commit *git.Commit) bool {
+		steps = append(steps, sequenceStep{Action: sequencePick, ID: commit.Id()})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("range %s has no commits to pick", revision)
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,268 @@
+package gong
+
+import (
+	"fmt"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+// MergeResult is the outcome of a previewed merge.
+type MergeResult string
+
+const (
+	MergeResultUpToDate    MergeResult = "up-to-date"
+	MergeResultFastForward MergeResult = "fast-forward"
+	MergeResultMerge       MergeResult = "merge"
+	MergeResultSquash      MergeResult = "squash"
+	MergeResultRefused     MergeResult = "refused"
+)
+
+// MergePreview describes what merging the source into the target would do.
+// Reason explains why a merge would be refused, and StatusCommand is the
+// command the merge policy of the target runs before merging.
+type MergePreview struct {
+	Source        string          `json:"source"`
+	Target        string          `json:"target"`
+	Strategy      MergeStrategy   `json:"strategy"`
+	Result        MergeResult     `json:"result"`
+	Reason        string          `json:"reason,omitempty"`
+	StatusCommand string          `json:"status_command,omitempty"`
+	Commits       []PreviewCommit `json:"commits"`
+	Files         []PreviewFile   `json:"files"`
+	Conflicts     []string        `json:"conflicts"`
+}
+
+// PreviewCommit is a commit a merge would bring in.
+type PreviewCommit struct {
+	ID      string `json:"id"`
+	Summary string `json:"summary"`
+	Author  string `json:"author"`
+}
+
+// PreviewFile is a file a merge would change. Status is A, M, D or R for an
+// added, modified, deleted or renamed file.
+type PreviewFile struct {
+	Status string `json:"status"`
+	Path   string `json:"path"`
+}
+
+// PreviewMerge runs the merge of the source into the current branch in memory
+// without touching the index or the working tree, and describes the result.
+// The source and the strategy are resolved like in Merge, and a merge the
+// merge policy of the branch does not allow is reported as refused. The status
+// command of the policy is not run.
+func (repo *Repository) PreviewMerge(source string, strategy MergeStrategy) (*MergePreview, error) {
+	destinationbranch, err := repo.Head.Branch()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(destinationbranch)
+
+	if strategy == "" {
+		strategy, err = DefaultMergeStrategy(destinationbranch.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	revision, err := repo.ResolveMergeSource(source)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(revision)
+
+	preview := &MergePreview{
+		Source:    mergeSourceName(revision),
+		Target:    destinationbranch.Name,
+		Strategy:  strategy,
+		Commits:   []PreviewCommit{},
+		Files:     []PreviewFile{},
+		Conflicts: []string{},
+	}
+
+	policy, policyErr := checkMergePolicy(destinationbranch.Name, revision, strategy)
+	preview.StatusCommand = policy.StatusCommand
+
+	theirAnnCommit, err := repo.annotatedCommit(revision)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(theirAnnCommit)
+
+	analysis, _, err := repo.Essence().MergeAnalysis([]*git.AnnotatedCommit{theirAnnCommit})
+	if err != nil {
+		return nil, err
+	}
+
+	if analysis&git.MergeAnalysisUpToDate != 0 {
+		preview.Result = MergeResultUpToDate
+		return preview, nil
+	}
+
+	ours, err := repo.Head.Commit()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(ours)
+
+	if preview.Commits, err = repo.previewCommits(ours.ID, revision.Commit.ID); err != nil {
+		return nil, err
+	}
+
+	ourTree, err := ours.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(ourTree)
+
+	fastForward := analysis&git.MergeAnalysisFastForward != 0
+
+	switch {
+	case fastForward && (strategy == MergeFastForward || strategy == MergeFastForwardOnly):
+		preview.Result = MergeResultFastForward
+
+		if policyErr != nil {
+			preview.Result = MergeResultRefused
+			preview.Reason = policyErr.Error()
+		}
+
+		theirTree, err := revision.Commit.Tree()
+		if err != nil {
+			return nil, err
+		}
+		defer Free(theirTree)
+
+		diff, err := repo.Essence().DiffTreeToTree(ourTree, theirTree, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer diff.Free()
+
+		preview.Files, err = previewFiles(diff, nil)
+
+		return preview, err
+	case policyErr != nil:
+		preview.Result = MergeResultRefused
+		preview.Reason = policyErr.Error()
+	case strategy == MergeFastForwardOnly:
+		preview.Result = MergeResultRefused
+		preview.Reason = fmt.Sprintf("%s has diverged from %s and the strategy is %s", destinationbranch.Name, preview.Source, strategy)
+	case strategy == MergeSquash:
+		preview.Result = MergeResultSquash
+	default:
+		preview.Result = MergeResultMerge
+	}
+
+	mergeOpts, err := git.DefaultMergeOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := repo.Essence().MergeCommits(ours.Essence(), revision.Commit.Essence(), &mergeOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(index)
+
+	conflicts, err := conflictedPaths(index)
+	if err != nil {
+		return nil, err
+	}
+
+	if conflicts != nil {
+		preview.Conflicts = conflicts
+	}
+
+	diff, err := repo.Essence().DiffTreeToIndex(ourTree, index, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer diff.Free()
+
+	preview.Files, err = previewFiles(diff, preview.Conflicts)
+
+	return preview, err
+}
+
+// previewCommits returns the commits reachable from theirs but not from ours,
+// newest first.
+func (repo *Repository) previewCommits(oursID *git.Oid, theirsID *git.Oid) ([]PreviewCommit, error) {
+	walk, err := repo.Essence().Walk()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(walk)
+
+	walk.Sorting(git.SortTopological | git.SortTime)
+
+	if err := walk.Push(theirsID); err != nil {
+		return nil, err
+	}
+
+	if err := walk.Hide(oursID); err != nil {
+		return nil, err
+	}
+
+	commits := []PreviewCommit{}
+
+	err = walk.Iterate(func(commit *git.Commit) bool {
+		commits = append(commits, PreviewCommit{
+			ID:      commit.Id().String(),
+			Summary: commit.Summary(),
+			Author:  commit.Author().Name,
+		})
+		return true
+	})
+
+	return commits, err
+}
+
+// previewFiles lists the files of the diff, leaving out the conflicted paths.
+func previewFiles(diff *git.Diff, conflicts []string) ([]PreviewFile, error) {
+	conflicted := make(map[string]bool)
+	for _, path := range conflicts {
+		conflicted[path] = true
+	}
+
+	count, err := diff.NumDeltas()
+	if err != nil {
+		return nil, err
+	}
+
+	files := []PreviewFile{}
+
+	for i := 0; i < count; i++ {
+		delta, err := diff.Delta(i)
+		if err != nil {
+			return nil, err
+		}
+
+		var status string
+
+		switch delta.Status {
+		case git.DeltaAdded:
+			status = "A"
+		case git.DeltaModified:
+			status = "M"
+		case git.DeltaRenamed:
+			status = "R"
+		case git.DeltaDeleted:
+			status = "D"
+		default:
+			continue
+		}
+
+		path := delta.NewFile.Path
+		if delta.Status == git.DeltaDeleted {
+			path = delta.OldFile.Path
+		}
+
+		if conflicted[path] {
+			continue
+		}
+
+		files = append(files, PreviewFile{Status: status, Path: path})
+	}
+
+	return files, nil
+}
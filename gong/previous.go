@@ -0,0 +1,230 @@
+package gong
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+// checkoutHistory is the state file where gong remembers the previously
+// checked-out branches and detached commits, numbered from the most recent.
+const checkoutHistory = "CHECKOUT_HISTORY"
+
+// checkoutHistoryLimit is the number of previous checkouts remembered.
+const checkoutHistoryLimit = 20
+
+var ErrNoPreviousCheckout = errors.New("no previous checkout found")
+
+// previousCheckoutPattern matches the @{-N} notation at the start of a revision.
+var previousCheckoutPattern = regexp.MustCompile(`^@\{-([1-9][0-9]*)\}`)
+
+// Checkout is a previously checked-out location. Either the Branch or the
+// Commit of a detached head is set.
+type Checkout struct {
+	Branch string
+	Commit string
+}
+
+// IsDetached reports whether the checkout was a detached commit.
+func (checkout *Checkout) IsDetached() bool {
+	return checkout.Branch == ""
+}
+
+func (checkout *Checkout) String() string {
+	if checkout.IsDetached() {
+		return checkout.Commit
+	}
+
+	return checkout.Branch
+}
+
+// ParsePreviousCheckout parses the previous checkout notation - or @{-N} and
+// returns N. The second return value is false when rev is not in the notation.
+func ParsePreviousCheckout(rev string) (int, bool) {
+	if rev == "-" {
+		return 1, true
+	}
+
+	matches := previousCheckoutPattern.FindStringSubmatch(rev)
+	if matches == nil || matches[0] != rev {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// PreviousCheckout returns the nth previously checked-out branch or detached
+// commit. The previous checkout is 1.
+func (repo *Repository) PreviousCheckout(n int) (*Checkout, error) {
+	history, err := repo.checkoutHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 1 || n > len(history) {
+		return nil, fmt.Errorf("%w: @{-%d}", ErrNoPreviousCheckout, n)
+	}
+
+	return history[n-1], nil
+}
+
+// CheckoutPrevious switches to the nth previously checked-out branch or
+// detached commit.
+func (repo *Repository) CheckoutPrevious(n int) (*Checkout, error) {
+	checkout, err := repo.PreviousCheckout(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkout.IsDetached() {
+		commit, err := repo.CheckoutCommit(checkout.Commit)
+		if err != nil {
+			return nil, err
+		}
+		defer Free(commit)
+
+		return checkout, nil
+	}
+
+	branch, err := repo.FindBranch(checkout.Branch, git.BranchLocal)
+	if err != nil {
+		return nil, fmt.Errorf("previous branch %s no longer exists", checkout.Branch)
+	}
+	Free(branch)
+
+	branch, err = repo.CheckoutBranch(checkout.Branch)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(branch)
+
+	return checkout, nil
+}
+
+// currentCheckout returns the branch or the detached commit head points to.
+// Nil is returned when head is unborn.
+func (repo *Repository) currentCheckout() (*Checkout, error) {
+	exists, err := repo.Head.Exists()
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	detached, err := repo.Head.IsDetached()
+	if err != nil {
+		return nil, err
+	}
+
+	if detached {
+		commit, err := repo.Head.Commit()
+		if err != nil {
+			return nil, err
+		}
+		defer Free(commit)
+
+		return &Checkout{Commit: commit.ID.String()}, nil
+	}
+
+	branch, err := repo.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(branch)
+
+	return &Checkout{Branch: branch.Name}, nil
+}
+
+// rememberCheckout records the location head pointed to before a checkout as
+// the previous checkout. Nothing is recorded if head did not move.
+func (repo *Repository) rememberCheckout(previous *Checkout) error {
+	if previous == nil {
+		return nil
+	}
+
+	current, err := repo.currentCheckout()
+	if err != nil {
+		return err
+	}
+
+	if current != nil && *current == *previous {
+		return nil
+	}
+
+	history, err := repo.checkoutHistory()
+	if err != nil {
+		return err
+	}
+
+	history = append([]*Checkout{previous}, history...)
+	if len(history) > checkoutHistoryLimit {
+		history = history[:checkoutHistoryLimit]
+	}
+
+	return repo.writeCheckoutHistory(history)
+}
+
+// renameCheckoutHistory replaces the renamed branch in the checkout history.
+func (repo *Repository) renameCheckoutHistory(oldName string, newName string) error {
+	history, err := repo.checkoutHistory()
+	if err != nil || len(history) == 0 {
+		return err
+	}
+
+	for _, checkout := range history {
+		if checkout.Branch == oldName {
+			checkout.Branch = newName
+		}
+	}
+
+	return repo.writeCheckoutHistory(history)
+}
+
+// writeCheckoutHistory records the history keyed by the position of each
+// checkout, e.g. 1 branch main or 2 commit 1a2b3c4.
+func (repo *Repository) writeCheckoutHistory(history []*Checkout) error {
+	values := make(map[string]string)
+
+	for i, checkout := range history {
+		value := fmt.Sprintf("branch %s", checkout.Branch)
+		if checkout.IsDetached() {
+			value = fmt.Sprintf("commit %s", checkout.Commit)
+		}
+
+		values[strconv.Itoa(i+1)] = value
+	}
+
+	return repo.writeState(checkoutHistory, values)
+}
+
+func (repo *Repository) checkoutHistory() ([]*Checkout, error) {
+	values, err := repo.readState(checkoutHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []*Checkout
+
+	for i := 1; i <= len(values); i++ {
+		fields := strings.SplitN(values[strconv.Itoa(i)], " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "branch":
+			history = append(history, &Checkout{Branch: fields[1]})
+		case "commit":
+			history = append(history, &Checkout{Commit: fields[1]})
+		}
+	}
+
+	return history, nil
+}
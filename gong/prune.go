@@ -0,0 +1,437 @@
+package gong
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erikjuhani/git-gong/config"
+	git "github.com/libgit2/git2go/v31"
+)
+
+const (
+	prunedRef = "refs/gong/pruned/"
+	// pruneMetaEntry is the tree entry of a prune record holding the branch
+	// metadata.
+	pruneMetaEntry = "meta"
+)
+
+// StaleBranch is a local branch that is a candidate for pruning.
+type StaleBranch struct {
+	*Branch
+	Merged     bool
+	LastCommit time.Time
+}
+
+// Reason describes why the branch is considered stale.
+func (stale *StaleBranch) Reason(defaultBranch string) string {
+	if stale.Merged {
+		return fmt.Sprintf("merged into %s", defaultBranch)
+	}
+
+	return fmt.Sprintf("no commits since %s", stale.LastCommit.Format("2006-01-02"))
+}
+
+// StaleBranches returns local branches that are fully merged into the default
+// branch or have had no commits in the given amount of days. A zero amount of
+// days only considers merged branches. The current branch, the default branch
+// and protected branches are never considered stale.
+func (repo *Repository) StaleBranches(days int) ([]*StaleBranch, error) {
+	defaultBranchName, err := repo.DefaultBranchName()
+	if err != nil {
+		return nil, err
+	}
+
+	defaultBranch, err := repo.FindBranch(defaultBranchName, git.BranchLocal)
+	if err != nil {
+		return nil, fmt.Errorf("no default branch found by branch name %s", defaultBranchName)
+	}
+	defer Free(defaultBranch)
+
+	currentBranch, err := repo.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(currentBranch)
+
+	branches, err := repo.Branches(git.BranchLocal)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := time.Now().AddDate(0, 0, -days)
+
+	var stale []*StaleBranch
+
+	for i, branch := range branches {
+		if branch.Name == currentBranch.Name ||
+			branch.Name == defaultBranch.Name ||
+			config.IsProtectedBranch(branch.Name) {
+			Free(branch)
+			continue
+		}
+
+		merged, lastCommit, err := repo.staleStatus(branch, defaultBranch)
+		if err != nil {
+			for _, branch := range stale {
+				Free(branch)
+			}
+			freeBranches(branches[i:])
+			return nil, err
+		}
+
+		if merged || (days > 0 && lastCommit.Before(threshold)) {
+			stale = append(stale, &StaleBranch{Branch: branch, Merged: merged, LastCommit: lastCommit})
+			continue
+		}
+
+		Free(branch)
+	}
+
+	return stale, nil
+}
+
+// staleStatus reports whether the branch is merged into the default branch
+// and when the last commit of the branch was made.
+func (repo *Repository) staleStatus(branch *Branch, defaultBranch *Branch) (bool, time.Time, error) {
+	merged := branch.ReferenceID.Equal(defaultBranch.ReferenceID)
+	if !merged {
+		var err error
+		merged, err = repo.Essence().DescendantOf(defaultBranch.ReferenceID, branch.ReferenceID)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+	}
+
+	commit, err := repo.FindCommit(branch.ReferenceID)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	defer Free(commit)
+
+	return merged, commit.Essence().Committer().When, nil
+}
+
+func freeBranches(branches []*Branch) {
+	for _, branch := range branches {
+		Free(branch)
+	}
+}
+
+// PruneBranch deletes a local branch. Each prune is recorded to
+// refs/gong/pruned/<branchname>@<n> so that the branch can be restored with
+// RestoreBranch. The record keeps the tip, the upstream, the metadata and the
+// auto-stash of the branch, which are removed along with the branch.
+func (repo *Repository) PruneBranch(branch *Branch) error {
+	if config.IsProtectedBranch(branch.Name) {
+		return fmt.Errorf("branch %s is protected, operation aborted", branch.Name)
+	}
+
+	records, err := repo.pruneRecords(branch.Name)
+	if err != nil {
+		return err
+	}
+
+	record := 1
+	if len(records) > 0 {
+		record = records[len(records)-1] + 1
+	}
+
+	values := make(map[string]string)
+	parents := []*git.Oid{branch.ReferenceID}
+
+	if branch.HasUpstream() {
+		values["upstream"] = branch.Upstream
+	}
+
+	stash, bound := repo.Stashes.Stashes()[branch.Name]
+	if bound {
+		values["stash"] = stash.ID.String()
+		parents = append(parents, stash.ID)
+	}
+
+	meta, err := repo.BranchMeta(branch.Name)
+	if err != nil {
+		return err
+	}
+
+	treeID, err := repo.pruneRecordTree(meta)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("gong: prune branch %s\n\n%s", branch.Name, formatState(values))
+
+	recordID, err := repo.Essence().CreateCommitFromIds("", signature(), signature(), message, treeID, parents...)
+	if err != nil {
+		return err
+	}
+
+	ref, err := repo.Essence().References.Create(pruneRecordName(branch.Name, record), recordID, false, message)
+	if err != nil {
+		return err
+	}
+	defer Free(ref)
+
+	if bound {
+		if err := repo.Stashes.unbind(stash); err != nil {
+			return err
+		}
+	}
+
+	if err := repo.SetBranchMeta(branch.Name, &BranchMeta{}); err != nil {
+		return err
+	}
+
+	return branch.Essence().Delete()
+}
+
+// pruneRecordTree returns a tree with the metadata of the pruned branch, or an
+// empty tree if the branch has no metadata.
+func (repo *Repository) pruneRecordTree(meta *BranchMeta) (*git.Oid, error) {
+	builder, err := repo.Essence().TreeBuilder()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(builder)
+
+	if !meta.Empty() {
+		blobID, err := repo.Essence().CreateBlobFromBuffer([]byte(meta.String()))
+		if err != nil {
+			return nil, err
+		}
+
+		if err := builder.Insert(pruneMetaEntry, blobID, git.FilemodeBlob); err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Write()
+}
+
+// DeleteBranch deletes a local branch other than the current branch. Like a
+// pruned branch the deleted branch can be restored with RestoreBranch.
+func (repo *Repository) DeleteBranch(branchName string) error {
+	branch, err := repo.FindBranch(branchName, git.BranchLocal)
+	if err != nil {
+		return fmt.Errorf("no branch found by branch name %s", branchName)
+	}
+	defer Free(branch)
+
+	head, err := branch.Essence().IsHead()
+	if err != nil {
+		return err
+	}
+
+	if head {
+		return fmt.Errorf("cannot delete the current branch %s, switch to another branch first", branchName)
+	}
+
+	return repo.PruneBranch(branch)
+}
+
+// PrunedBranches returns the names of the pruned branches that can be restored.
+func (repo *Repository) PrunedBranches() ([]string, error) {
+	refs, err := repo.References()
+	if err != nil && !git.IsErrorCode(err, git.ErrorCodeIterOver) {
+		return nil, err
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, ref := range refs {
+		branchName, _, ok := parsePruneRecord(ref)
+		if ok && !seen[branchName] {
+			seen[branchName] = true
+			names = append(names, branchName)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// PruneRecords returns the records of the pruned branch in the form
+// <branchname>@<n>, oldest first.
+func (repo *Repository) PruneRecords(branchName string) ([]string, error) {
+	records, err := repo.pruneRecords(branchName)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, record := range records {
+		names = append(names, strings.TrimPrefix(pruneRecordName(branchName, record), prunedRef))
+	}
+
+	return names, nil
+}
+
+// pruneRecords returns the record numbers of the pruned branch in ascending
+// order.
+func (repo *Repository) pruneRecords(branchName string) ([]int, error) {
+	refs, err := repo.References()
+	if err != nil && !git.IsErrorCode(err, git.ErrorCodeIterOver) {
+		return nil, err
+	}
+
+	var records []int
+
+	for _, ref := range refs {
+		name, record, ok := parsePruneRecord(ref)
+		if ok && name == branchName {
+			records = append(records, record)
+		}
+	}
+
+	sort.Ints(records)
+
+	return records, nil
+}
+
+func pruneRecordName(branchName string, record int) string {
+	return fmt.Sprintf("%s%s@%d", prunedRef, branchName, record)
+}
+
+// parsePruneRecord parses the branch name and the record number from a
+// reference name of a prune record.
+func parsePruneRecord(refName string) (string, int, bool) {
+	if !strings.HasPrefix(refName, prunedRef) {
+		return "", 0, false
+	}
+
+	name := strings.TrimPrefix(refName, prunedRef)
+
+	i := strings.LastIndex(name, "@")
+	if i < 0 {
+		return "", 0, false
+	}
+
+	record, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+
+	return name[:i], record, true
+}
+
+// RestoreBranch restores a pruned branch to its tip at the time of pruning,
+// along with its upstream, metadata and auto-stash. The name is either the
+// branch name, which restores the latest record, or a record
+// <branchname>@<n> listed by PruneRecords.
+func (repo *Repository) RestoreBranch(name string) (*Branch, error) {
+	records, err := repo.pruneRecords(name)
+	if err != nil {
+		return nil, err
+	}
+
+	branchName, record, ok := name, 0, len(records) > 0
+	if ok {
+		record = records[len(records)-1]
+	} else {
+		branchName, record, ok = parsePruneRecord(prunedRef + name)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("no pruned branch found by branch name %s", name)
+	}
+
+	ref, err := repo.Essence().References.Lookup(pruneRecordName(branchName, record))
+	if err != nil {
+		return nil, fmt.Errorf("no pruned branch found by %s", name)
+	}
+	defer Free(ref)
+
+	recordCommit, err := repo.FindCommit(ref.Target())
+	if err != nil {
+		return nil, err
+	}
+	defer Free(recordCommit)
+
+	var values map[string]string
+	if parts := strings.SplitN(recordCommit.Essence().Message(), "\n\n", 2); len(parts) == 2 {
+		values = parseState(parts[1])
+	}
+
+	commit, err := repo.FindCommit(recordCommit.Essence().ParentId(0))
+	if err != nil {
+		return nil, err
+	}
+	defer Free(commit)
+
+	branch, err := repo.createBranch(branchName, commit, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if upstream, ok := values["upstream"]; ok {
+		// The remote branch may have been deleted since the prune.
+		if err := branch.Essence().SetUpstream(upstream); err == nil {
+			branch.Upstream = upstream
+		}
+	}
+
+	if err := repo.restorePrunedMeta(branchName, recordCommit); err != nil {
+		return nil, err
+	}
+
+	if stashID, ok := values["stash"]; ok {
+		if err := repo.restorePrunedStash(branchName, stashID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ref.Delete(); err != nil {
+		return nil, err
+	}
+
+	return branch, nil
+}
+
+// restorePrunedMeta restores the metadata recorded to the tree of the prune
+// record.
+func (repo *Repository) restorePrunedMeta(branchName string, recordCommit *Commit) error {
+	tree, err := recordCommit.Tree()
+	if err != nil {
+		return err
+	}
+	defer Free(tree)
+
+	entry := tree.EntryByName(pruneMetaEntry)
+	if entry == nil {
+		return nil
+	}
+
+	blob, err := repo.Essence().LookupBlob(entry.Id)
+	if err != nil {
+		return err
+	}
+	defer Free(blob)
+
+	return repo.SetBranchMeta(branchName, parseBranchMeta(string(blob.Contents())))
+}
+
+// restorePrunedStash binds the recorded auto-stash to the restored branch if
+// the stash still exists in the stash list.
+func (repo *Repository) restorePrunedStash(branchName string, stashID string) error {
+	id, err := git.NewOid(stashID)
+	if err != nil {
+		return err
+	}
+
+	exists := false
+
+	err = repo.Stashes.Essence().Foreach(func(_ int, _ string, stashID *git.Oid) error {
+		exists = exists || stashID.Equal(id)
+		return nil
+	})
+	if err != nil || !exists {
+		return err
+	}
+
+	return repo.Stashes.Bind(branchName, id)
+}
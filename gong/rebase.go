@@ -0,0 +1,330 @@
+package gong
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/erikjuhani/git-gong/config"
+	git "github.com/libgit2/git2go/v31"
+)
+
+// rebaseState records a rebase that stopped because a commit conflicted.
+const rebaseState = "REBASE"
+
+// RebaseInProgress reports whether a stopped rebase waits to be continued,
+// skipped or aborted.
+func (repo *Repository) RebaseInProgress() (bool, error) {
+	state, err := repo.readState(rebaseState)
+	return state != nil, err
+}
+
+// Rebase replays the commits of the current branch that are not on the onto
+// revision on top of it. An empty onto rebases onto the upstream of the branch
+// or the default branch. The commits are replayed with an in-memory libgit2
+// rebase, and only when a commit conflicts the rebase stops with the conflict
+// markers in the working tree to be continued, skipped or aborted. Uncommitted
+// changes are stashed for the rebase like when switching branches. Commits
+// that are on a protected branch are never rewritten.
+func (repo *Repository) Rebase(onto string) (*Revision, error) {
+	seq, err := repo.newSequence(rebaseState)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkEmptyString(seq.State["branch"]) {
+		return nil, errors.New("cannot rebase a detached head, switch to a branch first")
+	}
+
+	branch, err := repo.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(branch)
+
+	if config.IsProtectedBranch(branch.Name) {
+		return nil, fmt.Errorf("branch %s is protected, rebasing would rewrite its commits", branch.Name)
+	}
+
+	if checkEmptyString(onto) {
+		onto = branch.Upstream
+	}
+
+	if checkEmptyString(onto) {
+		onto, err = repo.DefaultBranchName()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	revision, err := repo.ResolveRevision(onto)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := repo.Essence().MergeBase(branch.ReferenceID, revision.Commit.ID)
+	if err != nil {
+		Free(revision)
+		return nil, err
+	}
+
+	if base.Equal(revision.Commit.ID) {
+		Free(revision)
+		return nil, fmt.Errorf("branch %s is already based on %s, nothing to rebase", branch.Name, onto)
+	}
+
+	rebase, err := repo.initRebase(branch.ReferenceID, revision.Commit.ID, revision.Commit.ID)
+	if err != nil {
+		Free(revision)
+		return nil, err
+	}
+	defer Free(rebase)
+
+	if err := repo.checkProtectedCommits(rebaseSteps(rebase)); err != nil {
+		Free(revision)
+		return nil, err
+	}
+
+	if err := repo.stashForSequence(seq); err != nil {
+		Free(revision)
+		return nil, err
+	}
+
+	if err := repo.runRebase(seq, rebase, revision.Commit.ID); err != nil {
+		Free(revision)
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// ContinueRebase commits the conflicting commit of a stopped rebase after the
+// conflicts have been resolved, and replays the rest of the commits.
+func (repo *Repository) ContinueRebase() (*Branch, error) {
+	seq, steps, err := repo.readSequence(rebaseState, ErrNoRebaseInProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.continueStep(seq, steps[0]); err != nil {
+		return nil, err
+	}
+
+	if err := repo.resumeRebase(seq, steps[0]); err != nil {
+		return nil, err
+	}
+
+	return repo.FindBranch(seq.State["branch"], git.BranchLocal)
+}
+
+// SkipRebase drops the conflicting commit of a stopped rebase and replays the
+// rest of the commits.
+func (repo *Repository) SkipRebase() (*Branch, error) {
+	seq, steps, err := repo.readSequence(rebaseState, ErrNoRebaseInProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.skipStep(); err != nil {
+		return nil, err
+	}
+
+	if err := repo.resumeRebase(seq, steps[0]); err != nil {
+		return nil, err
+	}
+
+	return repo.FindBranch(seq.State["branch"], git.BranchLocal)
+}
+
+// AbortRebase stops a rebase and returns to the branch as it was before the
+// rebase, restoring the stashed changes.
+func (repo *Repository) AbortRebase() (*Branch, error) {
+	seq, _, err := repo.readSequence(rebaseState, ErrNoRebaseInProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.abortSequence(seq); err != nil {
+		return nil, err
+	}
+
+	return repo.FindBranch(seq.State["branch"], git.BranchLocal)
+}
+
+// initRebase starts an in-memory rebase of the commits reachable from the
+// branch commit but not from the upstream commit onto the onto commit. The
+// in-memory rebase leaves the references, the index and the working tree
+// untouched.
+func (repo *Repository) initRebase(branchID *git.Oid, upstreamID *git.Oid, ontoID *git.Oid) (*git.Rebase, error) {
+	var annotated []*git.AnnotatedCommit
+
+	defer func() {
+		for _, commit := range annotated {
+			Free(commit)
+		}
+	}()
+
+	for _, id := range []*git.Oid{branchID, upstreamID, ontoID} {
+		commit, err := repo.Essence().LookupAnnotatedCommit(id)
+		if err != nil {
+			return nil, err
+		}
+
+		annotated = append(annotated, commit)
+	}
+
+	opts, err := git.DefaultRebaseOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.InMemory = 1
+
+	return repo.Essence().InitRebase(annotated[0], annotated[1], annotated[2], &opts)
+}
+
+// rebaseSteps returns the commits the rebase replays, oldest first. libgit2
+// leaves merge commits out.
+func rebaseSteps(rebase *git.Rebase) []sequenceStep {
+	var steps []sequenceStep
+
+	for i := uint(0); i < rebase.OperationCount(); i++ {
+		steps = append(steps, sequenceStep{Action: sequencePick, ID: rebase.OperationAt(i).Id})
+	}
+
+	return steps
+}
+
+// runRebase replays the commits of the rebase on top of the onto commit. A
+// commit that is already applied is dropped. If a commit conflicts the
+// in-memory rebase is aborted, as it cannot be resumed by another gong
+// process, and the conflict is left to the working tree with the commits left
+// recorded in the state of the rebase. Otherwise the branch is moved to the
+// last replayed commit.
+func (repo *Repository) runRebase(seq *sequence, rebase *git.Rebase, ontoID *git.Oid) error {
+	headID := ontoID
+
+	for {
+		operation, err := rebase.Next()
+		if git.IsErrorCode(err, git.ErrorCodeIterOver) {
+			break
+		}
+		if err != nil {
+			_ = rebase.Abort()
+			return err
+		}
+
+		commit, err := repo.FindCommit(operation.Id)
+		if err != nil {
+			_ = rebase.Abort()
+			return err
+		}
+
+		commitID := new(git.Oid)
+		err = rebase.Commit(commitID, commit.Essence().Author(), signature(), commit.Essence().Message())
+		Free(commit)
+
+		switch {
+		case git.IsErrorCode(err, git.ErrorCodeApplied):
+			continue
+		case git.IsErrorCode(err, git.ErrorCodeUnmerged):
+			current, err := rebase.CurrentOperationIndex()
+			if err != nil {
+				_ = rebase.Abort()
+				return err
+			}
+
+			left := rebaseSteps(rebase)[current:]
+
+			if err := rebase.Abort(); err != nil {
+				return err
+			}
+
+			return repo.stopSequence(seq, headID, left)
+		case err != nil:
+			_ = rebase.Abort()
+			return err
+		}
+
+		headID = commitID
+	}
+
+	if err := rebase.Finish(); err != nil {
+		return err
+	}
+
+	return repo.finishSequence(seq, headID)
+}
+
+// resumeRebase replays the commits after the stopped commit on top of head
+// with a new in-memory rebase of the original branch.
+func (repo *Repository) resumeRebase(seq *sequence, stopped sequenceStep) error {
+	original, err := git.NewOid(seq.State["original"])
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head.Commit()
+	if err != nil {
+		return err
+	}
+	defer Free(head)
+
+	rebase, err := repo.initRebase(original, stopped.ID, head.ID)
+	if err != nil {
+		return err
+	}
+	defer Free(rebase)
+
+	return repo.runRebase(seq, rebase, head.ID)
+}
+
+// checkProtectedCommits refuses steps whose commits are already on a
+// protected branch, as replaying them would rewrite the protected history.
+// A remote branch e.g. origin/main is protected when its name on the remote
+// is, so that commits already shared are not rewritten either.
+func (repo *Repository) checkProtectedCommits(steps []sequenceStep) error {
+	branches, err := repo.Branches(git.BranchAll)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, branch := range branches {
+			Free(branch)
+		}
+	}()
+
+	for _, branch := range branches {
+		branchName := branch.Name
+		if branch.Essence().IsRemote() {
+			parts := strings.SplitN(branchName, "/", 2)
+			if len(parts) != 2 || parts[1] == "HEAD" {
+				continue
+			}
+			branchName = parts[1]
+		}
+
+		if !config.IsProtectedBranch(branchName) {
+			continue
+		}
+
+		for _, step := range steps {
+			onBranch := branch.ReferenceID.Equal(step.ID)
+			if !onBranch {
+				onBranch, err = repo.Essence().DescendantOf(branch.ReferenceID, step.ID)
+				if err != nil {
+					return err
+				}
+			}
+
+			if onBranch {
+				return fmt.Errorf(
+					"commit %s is already on the protected branch %s, rebasing would rewrite it",
+					step.ID.String()[:7], branch.Name,
+				)
+			}
+		}
+	}
+
+	return nil
+}
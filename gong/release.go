@@ -0,0 +1,343 @@
+package gong
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erikjuhani/git-gong/config"
+)
+
+const (
+	// LatestRelease is the release query for the highest released version.
+	LatestRelease = "latest"
+
+	// DefaultReleaseChannel is the channel of releases created without one.
+	DefaultReleaseChannel = "stable"
+
+	releaseChannelTrailer = "Release-Channel"
+	releaseDateTrailer    = "Release-Date"
+)
+
+var ErrReleaseNotFound = errors.New("no release found")
+
+var semverPattern = `(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(?:-([0-9A-Za-z.-]+))?`
+
+// releaseQueryPattern matches release version ranges e.g. ^1.2 or ~1.2.3.
+var releaseQueryPattern = regexp.MustCompile(`^([\^~]?)(0|[1-9][0-9]*)(?:\.(0|[1-9][0-9]*))?(?:\.(0|[1-9][0-9]*))?$`)
+
+// Version is a semantic version of a release.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+func (version Version) String() string {
+	if version.Prerelease != "" {
+		return fmt.Sprintf("%d.%d.%d-%s", version.Major, version.Minor, version.Patch, version.Prerelease)
+	}
+
+	return fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+}
+
+// Compare returns -1, 0 or 1 if the version is lower, equal or higher than
+// the other version. A prerelease is lower than the release of the same
+// version, prereleases are compared lexically.
+func (version Version) Compare(other Version) int {
+	for _, diff := range []int{
+		version.Major - other.Major,
+		version.Minor - other.Minor,
+		version.Patch - other.Patch,
+	} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+
+	switch {
+	case version.Prerelease == other.Prerelease:
+		return 0
+	case version.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	case version.Prerelease < other.Prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// Release is an annotated tag whose name matches the configured release
+// pattern. The notes, channel and date are stored in the tag message.
+type Release struct {
+	*Tag
+	Version Version
+	Notes   string
+	Channel string
+	Date    time.Time
+}
+
+// releasePattern returns the configured release tag name pattern.
+func releasePattern() string {
+	pattern := config.GetString(config.ReleasePatternKey)
+	if checkEmptyString(pattern) || !strings.Contains(pattern, "{version}") {
+		return config.DefaultReleasePattern
+	}
+
+	return pattern
+}
+
+func releaseNameRegexp() *regexp.Regexp {
+	parts := strings.SplitN(releasePattern(), "{version}", 2)
+	return regexp.MustCompile(fmt.Sprintf("^%s%s%s$", regexp.QuoteMeta(parts[0]), semverPattern, regexp.QuoteMeta(parts[1])))
+}
+
+// ParseReleaseName parses the version from a release tag name. The second
+// return value is false when the name does not match the release pattern.
+func ParseReleaseName(name string) (Version, bool) {
+	matches := releaseNameRegexp().FindStringSubmatch(name)
+	if matches == nil {
+		return Version{}, false
+	}
+
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+	patch, _ := strconv.Atoi(matches[3])
+
+	return Version{Major: major, Minor: minor, Patch: patch, Prerelease: matches[4]}, true
+}
+
+// ReleaseName returns the release tag name for the name, which is either
+// already a release tag name or a bare version e.g. 1.2.3.
+func ReleaseName(name string) (string, error) {
+	if _, ok := ParseReleaseName(name); ok {
+		return name, nil
+	}
+
+	releaseName := strings.Replace(releasePattern(), "{version}", name, 1)
+	if _, ok := ParseReleaseName(releaseName); ok {
+		return releaseName, nil
+	}
+
+	return "", fmt.Errorf("release name %s does not match the release pattern %s", name, releasePattern())
+}
+
+// formatReleaseMessage formats the notes and the metadata of a release as a
+// tag message with the metadata as trailers.
+func formatReleaseMessage(notes string, channel string, date time.Time) string {
+	sb := strings.Builder{}
+
+	if notes = strings.TrimSpace(notes); notes != "" {
+		sb.WriteString(notes)
+		sb.WriteString("\n\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("%s: %s\n", releaseChannelTrailer, channel))
+	sb.WriteString(fmt.Sprintf("%s: %s\n", releaseDateTrailer, date.Format(time.RFC3339)))
+
+	return sb.String()
+}
+
+// NewRelease creates a release from an annotated tag. Nil is returned if the
+// tag is not a release.
+func NewRelease(tag *Tag) *Release {
+	version, ok := ParseReleaseName(tag.Name)
+	if !ok {
+		return nil
+	}
+
+	release := &Release{Tag: tag, Version: version, Channel: DefaultReleaseChannel}
+
+	if tagger := tag.Essence().Tagger(); tagger != nil {
+		release.Date = tagger.When
+	}
+
+	var notes []string
+
+	for _, line := range strings.Split(strings.TrimSpace(tag.Essence().Message()), "\n") {
+		switch {
+		case strings.HasPrefix(line, releaseChannelTrailer+":"):
+			release.Channel = strings.TrimSpace(strings.TrimPrefix(line, releaseChannelTrailer+":"))
+		case strings.HasPrefix(line, releaseDateTrailer+":"):
+			date, err := time.Parse(time.RFC3339, strings.TrimSpace(strings.TrimPrefix(line, releaseDateTrailer+":")))
+			if err == nil {
+				release.Date = date
+			}
+		default:
+			notes = append(notes, line)
+		}
+	}
+
+	release.Notes = strings.TrimSpace(strings.Join(notes, "\n"))
+
+	return release
+}
+
+// Releases returns the releases of the repository from the highest version to
+// the lowest. Tags that are not annotated or do not match the release pattern
+// are not releases.
+func (repo *Repository) Releases() ([]*Release, error) {
+	tags, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []*Release
+
+	for _, tag := range tags {
+		if release := NewRelease(tag); release != nil {
+			releases = append(releases, release)
+		}
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].Version.Compare(releases[j].Version) > 0
+	})
+
+	return releases, nil
+}
+
+// FindRelease finds the release matching the query. The query is a release
+// name, a version, latest for the highest version that is not a prerelease,
+// or a version range such as ^1.2 or ~1.2.3 resolving to the highest
+// matching version.
+func (repo *Repository) FindRelease(query string) (*Release, error) {
+	releases, err := repo.Releases()
+	if err != nil {
+		return nil, err
+	}
+
+	if query == LatestRelease {
+		for _, release := range releases {
+			if release.Version.Prerelease == "" {
+				return release, nil
+			}
+		}
+
+		if len(releases) > 0 {
+			return releases[0], nil
+		}
+
+		return nil, fmt.Errorf("%w, the repository has no releases", ErrReleaseNotFound)
+	}
+
+	for _, release := range releases {
+		if release.Name == query || release.Version.String() == query {
+			return release, nil
+		}
+	}
+
+	matches, ok := releaseRange(query)
+	if ok {
+		for _, release := range releases {
+			if matches(release.Version) {
+				return release, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w by %s", ErrReleaseNotFound, query)
+}
+
+// releaseRange returns a function matching the versions in the range query.
+// ^1.2 matches versions >=1.2.0 and <2.0.0, ~1.2 matches >=1.2.0 and <1.3.0
+// and a partial version such as 1.2 matches any 1.2.x version. Prereleases
+// are never matched by a range.
+func releaseRange(query string) (func(Version) bool, bool) {
+	matches := releaseQueryPattern.FindStringSubmatch(query)
+	if matches == nil {
+		return nil, false
+	}
+
+	operator := matches[1]
+
+	var parts []int
+	for _, part := range matches[2:] {
+		if part == "" {
+			break
+		}
+
+		n, _ := strconv.Atoi(part)
+		parts = append(parts, n)
+	}
+
+	lower := Version{}
+	for i, n := range parts {
+		switch i {
+		case 0:
+			lower.Major = n
+		case 1:
+			lower.Minor = n
+		case 2:
+			lower.Patch = n
+		}
+	}
+
+	upper := lower
+	switch {
+	case operator == "^" && lower.Major > 0, len(parts) == 1:
+		upper = Version{Major: lower.Major + 1}
+	case operator == "^" && lower.Minor > 0, len(parts) == 2, operator == "~":
+		upper = Version{Major: lower.Major, Minor: lower.Minor + 1}
+	case operator == "^":
+		upper = Version{Major: lower.Major, Minor: lower.Minor, Patch: lower.Patch + 1}
+	default:
+		// A full version without an operator matches only itself, which has
+		// already been looked up.
+		return nil, false
+	}
+
+	return func(version Version) bool {
+		return version.Prerelease == "" && version.Compare(lower) >= 0 && version.Compare(upper) < 0
+	}, true
+}
+
+// CreateRelease creates a release of the current commit as an annotated tag.
+// The name is a release tag name or a bare version that is formatted with the
+// release pattern.
+func (repo *Repository) CreateRelease(name string, notes string, channel string) (*Release, error) {
+	releaseName, err := ReleaseName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkEmptyString(channel) {
+		channel = DefaultReleaseChannel
+	}
+
+	if _, err := repo.CreateTag(releaseName, formatReleaseMessage(notes, channel, time.Now())); err != nil {
+		return nil, err
+	}
+
+	return repo.FindRelease(releaseName)
+}
+
+// CheckoutRelease detaches head to the release matching the query.
+func (repo *Repository) CheckoutRelease(query string) (*Release, error) {
+	release, err := repo.FindRelease(query)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := repo.releaseRevision(query, release)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(revision)
+
+	if err := repo.checkoutRevision(revision); err != nil {
+		return nil, err
+	}
+
+	return release, nil
+}
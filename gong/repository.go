@@ -4,9 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -46,7 +46,7 @@ func NewRepository(gitRepo *git.Repository, index *git.Index) *Repository {
 		Path:    gitRepo.Workdir(),
 		GitPath: gitRepo.Path(),
 		Index:   index,
-		Stashes: NewStashCollection(&gitRepo.Stashes),
+		Stashes: NewStashCollection(gitRepo),
 		essence: gitRepo,
 	}
 }
@@ -91,141 +91,76 @@ func (repo *Repository) FindBranch(branchName string, branchType git.BranchType)
 	return NewBranch(branchName, gitBranch), nil
 }
 
-func (repo *Repository) Merge(branchName string) error {
-	destinationbranch, err := repo.Head.Branch()
+// Branches returns all branches of the given branch type.
+func (repo *Repository) Branches(branchType git.BranchType) ([]*Branch, error) {
+	iter, err := repo.Essence().NewBranchIterator(branchType)
 	if err != nil {
-		return err
-	}
-
-	sourceBranch, err := repo.FindBranch(branchName, git.BranchLocal)
-	if err != nil {
-		return err
-	}
-
-	theirAnnCommit, err := sourceBranch.AnnotatedCommit()
-	if err != nil {
-		return err
-	}
-	defer Free(theirAnnCommit)
-
-	mergeHeads := make([]*git.AnnotatedCommit, 1)
-	mergeHeads[0] = theirAnnCommit
-
-	analysis, _, err := repo.essence.MergeAnalysis(mergeHeads)
-	log.Println(err)
-	if err != nil {
-		return err
-	}
-
-	checkoutOpts := git.CheckoutOpts{
-		Strategy: git.CheckoutSafe | git.CheckoutRecreateMissing | git.CheckoutUseTheirs,
+		return nil, err
 	}
+	defer Free(iter)
 
-	switch {
-	case analysis&git.MergeAnalysisUnborn != 0:
-		// Head is unborn, merge is impossible
-		return errors.New("cannot merge head doest not exist")
-	case analysis&git.MergeAnalysisUpToDate != 0:
-		// Nothing to merge
-		return errors.New("merge failed, nothing to merge")
-	case analysis&git.MergeAnalysisFastForward != 0:
-		// History has not diverted so we fast forward and just add the commits on top
+	var branches []*Branch
 
-		sourceCommit, err := repo.FindCommit(sourceBranch.ReferenceID)
+	err = iter.ForEach(func(gitBranch *git.Branch, _ git.BranchType) error {
+		branchName, err := gitBranch.Name()
 		if err != nil {
 			return err
 		}
 
-		tree, err := repo.FindTree(sourceCommit.Essence().TreeId())
-		log.Println(err)
-		if err != nil {
-			return err
-		}
-
-		if err := repo.CheckoutTree(tree, &checkoutOpts); err != nil {
-			return err
-		}
-
-		if err := repo.Head.SetReference(sourceBranch.RefName); err != nil {
-			return err
-		}
-
+		branches = append(branches, NewBranch(branchName, gitBranch))
 		return nil
-	case analysis&git.MergeAnalysisNormal != 0:
-		mergeOpts, err := git.DefaultMergeOptions()
-		if err != nil {
-			return err
-		}
-
-		mergeOpts.FileFavor = git.MergeFileFavorNormal
-		mergeOpts.TreeFlags = git.MergeTreeFailOnConflict
-
-		if err := repo.Essence().Merge(mergeHeads, &mergeOpts, &checkoutOpts); err != nil {
-			return err
-		}
+	})
 
-		index, err := repo.Essence().Index()
-		if err != nil {
-			return err
-		}
-		defer Free(index)
+	return branches, err
+}
 
-		if index.HasConflicts() {
-			return errors.New("merge conflict, cannot merge. Fix conflicts then commit before merge")
-		}
+func (repo *Repository) Info() (string, error) {
+	currentBranch, err := repo.CurrentBranch()
+	if err != nil {
+		return "", err
+	}
 
-		theirCommit, err := repo.FindCommit(sourceBranch.ReferenceID)
-		if err != nil {
-			return err
-		}
-		defer Free(theirCommit)
+	currentTip, err := repo.Head.Commit()
+	if err != nil {
+		return "", err
+	}
 
-		treeID, err := index.WriteTree()
-		if err != nil {
-			return err
-		}
+	sb := strings.Builder{}
 
-		tree, err := repo.FindTree(treeID)
-		if err != nil {
-			return err
-		}
-		defer Free(tree)
+	meta, err := repo.BranchMeta(currentBranch.Name)
+	if err != nil {
+		return "", err
+	}
 
-		ourCommit, err := repo.Head.Commit()
-		if err != nil {
-			return err
-		}
-		defer Free(ourCommit)
+	sb.WriteString(fmt.Sprintf("Branch %s\n", currentBranch.Name))
+	sb.WriteString(fmt.Sprintf("Commit %s\n", currentTip.ID.String()))
 
-		mergeMessage := fmt.Sprintf("Merge %s into %s", sourceBranch.Name, destinationbranch.Name)
+	if repo.IsLinkedWorktree() {
+		sb.WriteString(fmt.Sprintf("Worktree %s\n", filepath.Clean(repo.Path)))
+	}
 
-		mergeCommit, err := repo.CreateCommit(tree, mergeMessage, ourCommit, theirCommit)
+	if currentBranch.HasUpstream() {
+		ahead, behind, err := currentBranch.AheadBehind()
 		if err != nil {
-			return err
+			return "", err
 		}
-		defer Free(mergeCommit)
 
-		return repo.Essence().StateCleanup()
+		sb.WriteString(fmt.Sprintf("Upstream %s (ahead %d, behind %d)\n", currentBranch.Upstream, ahead, behind))
 	}
 
-	return nil
-}
-
-func (repo *Repository) Info() (string, error) {
-	currentBranch, err := repo.CurrentBranch()
-	if err != nil {
-		return "", err
+	if !checkEmptyString(meta.Description) {
+		sb.WriteString(fmt.Sprintf("Description %s\n", meta.Description))
 	}
 
-	currentTip, err := repo.Head.Commit()
-	if err != nil {
-		return "", err
+	if !checkEmptyString(meta.Owner) {
+		sb.WriteString(fmt.Sprintf("Owner %s\n", meta.Owner))
 	}
 
-	sb := strings.Builder{}
+	if !checkEmptyString(meta.Ticket) {
+		sb.WriteString(fmt.Sprintf("Ticket %s\n", meta.Ticket))
+	}
 
-	sb.WriteString(fmt.Sprintf("Branch %s\n", currentBranch.Name))
-	sb.WriteString(fmt.Sprintf("Commit %s\n\n", currentTip.ID.String()))
+	sb.WriteString("\n")
 
 	entries, err := repo.StatusEntries()
 	if err != nil {
@@ -323,16 +258,16 @@ func (repo *Repository) Tags() ([]*Tag, error) {
 		defer Free(ref)
 
 		if ref.IsTag() {
+			// Lightweight tags do not peel to a tag object.
 			tagObj, err := ref.Peel(git.ObjectTag)
 			if err != nil {
-				return err
+				return nil
 			}
 
 			tag, err := tagObj.AsTag()
 			if err != nil {
 				return err
 			}
-			defer Free(tag)
 
 			tags = append(tags, NewTag(tag))
 		}
@@ -353,20 +288,13 @@ func (repo *Repository) FindTag(tagName string) (*Tag, error) {
 		return nil, err
 	}
 
-	var tag *Tag
-
-	for _, tag = range tags {
+	for _, tag := range tags {
 		if tag.Name == tagName {
-			break
+			return tag, nil
 		}
 	}
 
-	if tag == nil {
-		return nil, fmt.Errorf("no tag found by tag name %s", tagName)
-	}
-	defer Free(tag)
-
-	return tag, nil
+	return nil, fmt.Errorf("no tag found by tag name %s", tagName)
 }
 
 func (repo *Repository) FindCommit(commitID *git.Oid) (*Commit, error) {
@@ -382,92 +310,94 @@ func (repo *Repository) CheckoutTree(tree *git.Tree, opts *git.CheckoutOptions)
 	return repo.Essence().CheckoutTree(tree, opts)
 }
 
-func (repo *Repository) CheckoutTag(tagName string) (*Tag, error) {
-	checkoutOpts := &git.CheckoutOpts{
-		Strategy: git.CheckoutSafe | git.CheckoutRecreateMissing | git.CheckoutAllowConflicts | git.CheckoutUseTheirs,
+// CheckoutTag detaches head to the commit of the tag.
+func (repo *Repository) CheckoutTag(tagName string) (*Revision, error) {
+	revision, err := repo.ResolveRevision(tagRef + tagName)
+	if err != nil {
+		return nil, fmt.Errorf("no tag found by tag name %s", tagName)
 	}
 
-	tags, err := repo.Tags()
-	if err != nil {
+	if err := repo.checkoutRevision(revision); err != nil {
+		Free(revision)
 		return nil, err
 	}
 
-	var tag *Tag
+	return revision, nil
+}
 
-	for _, tag = range tags {
-		if tag.Name == tagName {
-			break
-		}
+// CheckoutCommit detaches head to the commit the revision resolves to.
+func (repo *Repository) CheckoutCommit(rev string) (*Commit, error) {
+	revision, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return nil, err
 	}
 
-	if tag == nil {
-		return nil, fmt.Errorf("no tag found by tag name %s", tagName)
+	if err := repo.checkoutRevision(revision); err != nil {
+		Free(revision)
+		return nil, err
 	}
-	defer Free(tag)
 
-	commit, err := repo.FindCommit(tag.Essence().TargetId())
+	return revision.Commit, nil
+}
+
+// checkoutRevision detaches head to the commit of the resolved revision.
+func (repo *Repository) checkoutRevision(revision *Revision) error {
+	previous, err := repo.currentCheckout()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer Free(commit)
 
-	tree, err := commit.Tree()
+	checkoutOpts := &git.CheckoutOpts{
+		Strategy: git.CheckoutSafe | git.CheckoutRecreateMissing | git.CheckoutAllowConflicts | git.CheckoutUseTheirs,
+	}
+
+	tree, err := revision.Commit.Tree()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer Free(tree)
 
 	if err := repo.CheckoutTree(tree, checkoutOpts); err != nil {
-		return nil, err
+		return err
 	}
 
-	if err := repo.Head.Detach(commit.ID); err != nil {
-		return nil, err
+	if err := repo.Head.Detach(revision.Commit.ID); err != nil {
+		return err
 	}
 
-	return tag, nil
+	return repo.rememberCheckout(previous)
 }
 
-func (repo *Repository) CheckoutCommit(hash string) (*Commit, error) {
-	checkoutOpts := &git.CheckoutOpts{
-		Strategy: git.CheckoutSafe | git.CheckoutRecreateMissing | git.CheckoutAllowConflicts | git.CheckoutUseTheirs,
+func (repo *Repository) CheckoutBranch(branchName string) (*Branch, error) {
+	if err := repo.checkSequenceAllowed(); err != nil {
+		return nil, err
 	}
 
-	commits, err := repo.Commits()
+	previous, err := repo.currentCheckout()
 	if err != nil {
 		return nil, err
 	}
 
-	var commit *Commit
-	for _, commit = range commits {
-		if commit.ID.String() == hash {
-			break
-		}
-	}
+	branch, err := repo.FindBranch(branchName, git.BranchLocal)
 
-	if commit == nil {
-		return nil, fmt.Errorf("no commit found by hash %s", hash)
+	// Branch does not exist, create it first from a matching remote branch or
+	// from head.
+	if branch == nil || err != nil {
+		branch, err = repo.createCheckoutBranch(branchName)
+		if err != nil {
+			return nil, err
+		}
 	}
-	defer Free(commit)
 
-	tree, err := commit.Tree()
+	worktree, err := repo.worktreeOf(branchName)
 	if err != nil {
 		return nil, err
 	}
-	defer Free(tree)
-
-	if err := repo.Essence().CheckoutTree(tree, checkoutOpts); err != nil {
-		return nil, err
-	}
 
-	if err := repo.Head.Detach(commit.ID); err != nil {
-		return nil, err
+	if worktree != nil {
+		return nil, fmt.Errorf("branch %s is checked out in the worktree %s", branchName, worktree.Path)
 	}
 
-	return commit, err
-}
-
-func (repo *Repository) CheckoutBranch(branchName string) (*Branch, error) {
 	detached, err := repo.Head.IsDetached()
 	if err != nil {
 		return nil, err
@@ -489,16 +419,6 @@ func (repo *Repository) CheckoutBranch(branchName string) (*Branch, error) {
 		}
 	}
 
-	branch, err := repo.FindBranch(branchName, git.BranchLocal)
-
-	// Branch does not exist, create it first
-	if branch == nil || err != nil {
-		branch, err = repo.CreateLocalBranch(branchName)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	currentBranch, err := repo.CurrentBranch()
 	if err != nil {
 		return nil, err
@@ -540,12 +460,90 @@ func (repo *Repository) CheckoutBranch(branchName string) (*Branch, error) {
 		return nil, err
 	}
 
+	if err := repo.rememberCheckout(previous); err != nil {
+		return nil, err
+	}
+
 	// No existing stash.
 	if !repo.Stashes.Has(branch) {
 		return branch, nil
 	}
 
-	if err := repo.Stashes.Pop(branch); err != nil {
+	if err := repo.popSwitchStash(currentBranch.Name, branch); err != nil {
+		return nil, err
+	}
+
+	return branch, nil
+}
+
+// createCheckoutBranch creates the branch switched to when it does not exist
+// locally. If a remote has a branch with the same name the branch is created
+// from it and set to track it, otherwise the branch is created from head.
+func (repo *Repository) createCheckoutBranch(branchName string) (*Branch, error) {
+	remoteBranches, err := repo.RemoteBranches(branchName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(remoteBranches) {
+	case 0:
+		return repo.CreateLocalBranch(branchName)
+	case 1:
+		return repo.CreateBranchFrom(branchName, remoteBranches[0], true)
+	default:
+		return nil, fmt.Errorf(
+			"%w: %s matches %s, create the branch with gong create branch %s --from <remote>/%s --track",
+			ErrAmbiguousRemoteBranch, branchName, strings.Join(remoteBranches, ", "), branchName, branchName,
+		)
+	}
+}
+
+// StashBranch creates a new branch from the commit the stash was created on,
+// switches to the branch and pops the stash onto it.
+func (repo *Repository) StashBranch(branchName string, stash *Stash) (*Branch, error) {
+	// A stash left bound to the name of a deleted branch would be popped onto
+	// the new branch, and a stash of the current branch would be in the way of
+	// stashing its changes on the switch.
+	if repo.Stashes.bound(stashRef + branchName) {
+		return nil, fmt.Errorf("branch %s already has stashed changes, drop the stash first", branchName)
+	}
+
+	changed, err := repo.Changed()
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		currentBranch, err := repo.CurrentBranch()
+		if err != nil {
+			return nil, err
+		}
+		defer Free(currentBranch)
+
+		if repo.Stashes.Has(currentBranch) {
+			return nil, fmt.Errorf("branch %s already has stashed changes, commit the changes or drop the stash first", currentBranch.Name)
+		}
+	}
+
+	stashCommit, err := repo.FindCommit(stash.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(stashCommit)
+
+	baseCommit := stashCommit.Parent()
+	defer Free(baseCommit)
+
+	branch, err := repo.createBranch(branchName, baseCommit, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := repo.CheckoutBranch(branchName); err != nil {
+		return nil, err
+	}
+
+	if err := repo.Stashes.PopStash(stash); err != nil {
 		return nil, err
 	}
 
@@ -631,13 +629,27 @@ func (repo *Repository) Changed() (bool, error) {
 	return true, nil
 }
 
+// trackedChanges reports whether the index or the tracked files in the
+// working tree differ from head. Untracked files are not changes.
+func (repo *Repository) trackedChanges() (bool, error) {
+	status, err := repo.Essence().StatusList(&git.StatusOptions{Show: git.StatusShowIndexAndWorkdir})
+	if err != nil {
+		return false, err
+	}
+	defer Free(status)
+
+	entryCount, err := status.EntryCount()
+
+	return entryCount > 0, err
+}
+
 func (repo *Repository) AddToIndex(pathspec []string) (*git.Tree, error) {
 	branch, err := repo.Head.Branch()
 	if err != nil {
 		return nil, err
 	}
 
-	if config.ProtectedBranchPatterns.Match(branch.Name) {
+	if config.IsProtectedBranch(branch.Name) {
 		return nil, errors.New("trying to commit on a protected branch, operation aborted")
 	}
 
@@ -804,9 +816,44 @@ func (repo *Repository) CreateLocalBranch(branchName string) (branch *Branch, er
 	return repo.createBranch(branchName, headCommit, false)
 }
 
+// CreateBranchFrom creates a local branch starting from the given revision.
+// The revision can be a branch, tag, release, remote branch or a commit hash.
+// If track is true the revision must be a remote branch, which is then set
+// as the upstream of the created branch.
+func (repo *Repository) CreateBranchFrom(branchName string, rev string, track bool) (*Branch, error) {
+	if localBranch, err := repo.FindBranch(branchName, git.BranchLocal); err == nil {
+		return localBranch, fmt.Errorf("branch %s already exists", branchName)
+	}
+
+	revision, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(revision)
+
+	if track && revision.Kind != RevisionRemoteBranch {
+		return nil, fmt.Errorf("cannot track %s, revision is not a remote branch", rev)
+	}
+
+	commit := revision.Commit
+
+	branch, err := repo.createBranch(branchName, commit, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if track {
+		if err := branch.Essence().SetUpstream(revision.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return branch, nil
+}
+
 func (repo *Repository) createBranch(branchName string, commit *Commit, force bool) (*Branch, error) {
 	if !config.AllowedBranchPatterns.Match(branchName) {
-		return nil, errors.New("error branch name did not match allowed template patterns")
+		return nil, errBranchNameNotAllowed(branchName)
 	}
 
 	gitBranch, err := repo.Essence().CreateBranch(branchName, commit.Essence(), force)
@@ -817,6 +864,143 @@ func (repo *Repository) createBranch(branchName string, commit *Commit, force bo
 	return NewBranch(branchName, gitBranch), nil
 }
 
+// RenameBranch renames a local branch from oldName to newName.
+// The reflog, the upstream configuration, the auto-stash, the branch metadata,
+// the checkout history and HEAD (when oldName is the current branch) are moved
+// along with the branch reference. If the renamed
+// branch is the default branch, the default branch is set to newName.
+func (repo *Repository) RenameBranch(oldName string, newName string) (*Branch, error) {
+	if !config.AllowedBranchPatterns.Match(newName) {
+		return nil, errBranchNameNotAllowed(newName)
+	}
+
+	branch, err := repo.FindBranch(oldName, git.BranchLocal)
+	if err != nil {
+		return nil, fmt.Errorf("no branch found by branch name %s", oldName)
+	}
+	defer Free(branch)
+
+	if existing, err := repo.FindBranch(newName, git.BranchLocal); err == nil {
+		defer Free(existing)
+		return nil, fmt.Errorf("branch %s already exists", newName)
+	}
+
+	defaultBranch, err := repo.DefaultBranchName()
+	if err != nil {
+		return nil, err
+	}
+
+	gitBranch, err := branch.Essence().Move(newName, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.Stashes.Rename(oldName, newName); err != nil {
+		return nil, err
+	}
+
+	if err := repo.moveBranchMeta(oldName, newName); err != nil {
+		return nil, err
+	}
+
+	if err := repo.renameCheckoutHistory(oldName, newName); err != nil {
+		return nil, err
+	}
+
+	if defaultBranch == oldName {
+		if err := repo.SetDefaultBranchName(newName); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewBranch(newName, gitBranch), nil
+}
+
+// MigrateDefaultBranch renames the branch from to branch to and marks it as
+// the default branch of the repository, e.g. master -> main.
+func (repo *Repository) MigrateDefaultBranch(from string, to string) (*Branch, error) {
+	branch, err := repo.RenameBranch(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.SetDefaultBranchName(to); err != nil {
+		return nil, err
+	}
+
+	return branch, nil
+}
+
+// DefaultBranchName returns the name of the default branch. The name is read
+// from the gong.defaultBranch key of the repository configuration, and falls
+// back to the branch the HEAD of the default remote points to and then to
+// DefaultReference. init.defaultBranch is not used, as it only names the
+// branches of new repositories.
+func (repo *Repository) DefaultBranchName() (string, error) {
+	cfg, err := repo.localConfig()
+	if err != nil {
+		return "", err
+	}
+	defer Free(cfg)
+
+	if name, err := cfg.LookupString(defaultBranchKey); err == nil && !checkEmptyString(name) {
+		return name, nil
+	}
+
+	if name, ok := repo.remoteDefaultBranchName(); ok {
+		return name, nil
+	}
+
+	return DefaultReference, nil
+}
+
+// SetDefaultBranchName stores the default branch name to the repository configuration.
+func (repo *Repository) SetDefaultBranchName(branchName string) error {
+	cfg, err := repo.localConfig()
+	if err != nil {
+		return err
+	}
+	defer Free(cfg)
+
+	return cfg.SetString(defaultBranchKey, branchName)
+}
+
+// localConfig returns the configuration of the repository without the global
+// and system configuration.
+func (repo *Repository) localConfig() (*git.Config, error) {
+	cfg, err := repo.Essence().Config()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(cfg)
+
+	return cfg.OpenLevel(cfg, git.ConfigLevelLocal)
+}
+
+// remoteDefaultBranchName returns the branch refs/remotes/<remote>/HEAD of the
+// default remote points to, if the remote has one.
+func (repo *Repository) remoteDefaultBranchName() (string, bool) {
+	remote, err := repo.DefaultRemote()
+	if err != nil {
+		return "", false
+	}
+
+	remoteHeadRef := fmt.Sprintf("%s%s/", remoteRef, remote)
+
+	ref, err := repo.Essence().References.Lookup(remoteHeadRef + "HEAD")
+	if err != nil {
+		return "", false
+	}
+	defer Free(ref)
+
+	target := ref.SymbolicTarget()
+	if !strings.HasPrefix(target, remoteHeadRef) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(target, remoteHeadRef), true
+}
+
 // TODO get signature from git configuration
 func signature() *git.Signature {
 	return &git.Signature{
@@ -0,0 +1,108 @@
+package gong
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/erikjuhani/git-gong/config"
+)
+
+// revertState records a revert that stopped because a commit conflicted.
+const revertState = "REVERT"
+
+// RevertInProgress reports whether a stopped revert waits to be continued or
+// aborted.
+func (repo *Repository) RevertInProgress() (bool, error) {
+	state, err := repo.readState(revertState)
+	return state != nil, err
+}
+
+// Revert creates a commit undoing the changes of each commit the revisions
+// resolve to, in the given order. Unlike undoing a commit the history is not
+// rewritten, so commits that are already shared can be reverted. Merge commits
+// are reverted relative to the mainline parent, counting from 1. If a commit
+// conflicts the revert stops with the conflict markers in the working tree to
+// be continued or aborted. Reverting creates commits, so like committing it is
+// refused on a protected branch. The number of created commits is returned,
+// leaving out the reverts that changed nothing.
+func (repo *Repository) Revert(revisions []string, mainline uint) (int, error) {
+	seq, err := repo.newSequence(revertState)
+	if err != nil {
+		return 0, err
+	}
+
+	if config.IsProtectedBranch(seq.State["branch"]) {
+		return 0, errors.New("trying to commit on a protected branch, operation aborted")
+	}
+
+	var steps []sequenceStep
+
+	for _, revision := range revisions {
+		resolved, err := repo.ResolveRevision(revision)
+		if err != nil {
+			return 0, err
+		}
+
+		steps = append(steps, sequenceStep{Action: sequenceRevert, ID: resolved.Commit.ID})
+		Free(resolved)
+	}
+
+	if len(steps) == 0 {
+		return 0, errors.New("nothing to revert")
+	}
+
+	if err := repo.stashForSequence(seq); err != nil {
+		return 0, err
+	}
+
+	seq.State["mainline"] = strconv.FormatUint(uint64(mainline), 10)
+
+	head, err := repo.Head.Commit()
+	if err != nil {
+		return 0, err
+	}
+	defer Free(head)
+
+	if err := repo.runSequence(seq, head.ID, steps); err != nil {
+		return 0, err
+	}
+
+	return repo.createdCommits(head.ID)
+}
+
+// ContinueRevert commits the conflicting revert of a stopped revert after the
+// conflicts have been resolved, and reverts the rest of the commits.
+func (repo *Repository) ContinueRevert() error {
+	seq, steps, err := repo.readSequence(revertState, ErrNoRevertInProgress)
+	if err != nil {
+		return err
+	}
+
+	return repo.continueSequence(seq, steps)
+}
+
+// AbortRevert stops a revert and returns head to the commit it was before the
+// revert, restoring the stashed changes.
+func (repo *Repository) AbortRevert() error {
+	seq, _, err := repo.readSequence(revertState, ErrNoRevertInProgress)
+	if err != nil {
+		return err
+	}
+
+	return repo.abortSequence(seq)
+}
+
+// revertMessage returns the message of the commit reverting the commit. The
+// revert of a merge commit also refers to the mainline parent.
+func revertMessage(seq *sequence, commit *Commit) string {
+	message := fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s", commit.Essence().Summary(), commit.ID.String())
+
+	if mainline := seq.mainline(); commit.Essence().ParentCount() > 1 && mainline > 0 {
+		if parent := commit.Essence().ParentId(mainline - 1); parent != nil {
+			message += fmt.Sprintf(", reversing\nchanges made to %s", parent.String())
+		}
+	}
+
+	return message + ".\n"
+}
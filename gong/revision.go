@@ -0,0 +1,286 @@
+package gong
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+var (
+	ErrRevisionNotFound  = errors.New("no revision found")
+	ErrAmbiguousRevision = errors.New("revision is ambiguous")
+)
+
+const (
+	tagRef    = "refs/tags/"
+	remoteRef = "refs/remotes/"
+)
+
+// shortHashPattern matches revisions that can be an abbreviated commit hash.
+var shortHashPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// plainNamePattern matches revisions that are a plain name without any
+// revision operators, e.g. main, v1.0.0 or origin/main.
+var plainNamePattern = regexp.MustCompile(`^[^~^@:{}]+$`)
+
+type RevisionKind int
+
+const (
+	RevisionCommit RevisionKind = iota
+	RevisionBranch
+	RevisionRemoteBranch
+	RevisionTag
+	RevisionRelease
+)
+
+func (kind RevisionKind) String() string {
+	switch kind {
+	case RevisionBranch:
+		return "branch"
+	case RevisionRemoteBranch:
+		return "remote branch"
+	case RevisionTag:
+		return "tag"
+	case RevisionRelease:
+		return "release"
+	default:
+		return "commit"
+	}
+}
+
+// Revision is a resolved revision. Name is the short name of the reference
+// the revision resolved to, or the commit hash if it did not resolve to a
+// reference.
+type Revision struct {
+	Spec    string
+	Kind    RevisionKind
+	Name    string
+	RefName string
+	Commit  *Commit
+}
+
+func (rev *Revision) String() string {
+	return fmt.Sprintf("%s %s", rev.Kind, rev.Name)
+}
+
+func (rev *Revision) Free() {
+	Free(rev.Commit)
+}
+
+// ResolveRevision resolves a revision to a commit. The revision can be a
+// branch, remote branch, tag, full or abbreviated commit hash, and can use
+// the revision operators HEAD~n, ^n, @{-n} for the nth previous checkout,
+// @{n} for the reflog, <ref>@{date} e.g. main@{yesterday} and :/message for
+// the youngest commit with a matching message. A revision that does not
+// resolve otherwise is resolved as a release query e.g. latest, 1.2.0 or ^1.2,
+// and tags named like releases resolve to releases.
+func (repo *Repository) ResolveRevision(spec string) (*Revision, error) {
+	if checkEmptyString(spec) {
+		return nil, fmt.Errorf("%w by an empty revision", ErrRevisionNotFound)
+	}
+
+	expanded, err := repo.expandPreviousCheckout(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if plainNamePattern.MatchString(expanded) {
+		if err := repo.checkAmbiguousName(expanded); err != nil {
+			return nil, err
+		}
+	}
+
+	obj, ref, err := repo.Essence().RevparseExt(expanded)
+	if git.IsErrorCode(err, git.ErrorCodeAmbiguous) {
+		return nil, repo.errAmbiguousHash(spec, expanded)
+	}
+	if err != nil {
+		if release, err := repo.FindRelease(expanded); err == nil {
+			return repo.releaseRevision(spec, release)
+		}
+
+		return nil, fmt.Errorf("%w by %s", ErrRevisionNotFound, spec)
+	}
+	defer Free(obj)
+
+	revision := &Revision{Spec: spec, Kind: RevisionCommit}
+
+	if ref != nil {
+		defer Free(ref)
+
+		revision.RefName = ref.Name()
+		revision.Name = ref.Shorthand()
+
+		switch {
+		case ref.IsBranch():
+			revision.Kind = RevisionBranch
+		case ref.IsRemote():
+			revision.Kind = RevisionRemoteBranch
+		case ref.IsTag():
+			revision.Kind = RevisionTag
+
+			if repo.isReleaseTag(ref) {
+				revision.Kind = RevisionRelease
+			}
+		}
+	}
+
+	commitObj, err := obj.Peel(git.ObjectCommit)
+	if err != nil {
+		return nil, fmt.Errorf("revision %s does not point to a commit", spec)
+	}
+	defer Free(commitObj)
+
+	gitCommit, err := commitObj.AsCommit()
+	if err != nil {
+		return nil, err
+	}
+
+	revision.Commit = NewCommit(gitCommit)
+
+	if revision.Kind == RevisionCommit {
+		revision.Name = revision.Commit.ID.String()
+	}
+
+	return revision, nil
+}
+
+// isReleaseTag reports whether the tag reference is a release. Like in
+// Releases, only annotated tags named like a release are releases.
+func (repo *Repository) isReleaseTag(ref *git.Reference) bool {
+	if _, ok := ParseReleaseName(ref.Shorthand()); !ok {
+		return false
+	}
+
+	// Lightweight tags do not peel to a tag object.
+	tagObj, err := ref.Peel(git.ObjectTag)
+	if err != nil {
+		return false
+	}
+	Free(tagObj)
+
+	return true
+}
+
+// releaseRevision returns the revision of the release found by the spec.
+func (repo *Repository) releaseRevision(spec string, release *Release) (*Revision, error) {
+	commit, err := repo.FindCommit(release.CommitID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Revision{Spec: spec, Kind: RevisionRelease, Name: release.Name, RefName: tagRef + release.Name, Commit: commit}, nil
+}
+
+// expandPreviousCheckout replaces a leading - or @{-n} with the previously
+// checked-out branch or commit, so that e.g. @{-1}~2 can be resolved.
+func (repo *Repository) expandPreviousCheckout(spec string) (string, error) {
+	n, ok := ParsePreviousCheckout(spec)
+	rest := ""
+
+	if !ok {
+		matches := previousCheckoutPattern.FindStringSubmatchIndex(spec)
+		if matches == nil {
+			return spec, nil
+		}
+
+		n, ok = ParsePreviousCheckout(spec[:matches[1]])
+		if !ok {
+			return spec, nil
+		}
+		rest = spec[matches[1]:]
+	}
+
+	checkout, err := repo.PreviousCheckout(n)
+	if err != nil {
+		return "", err
+	}
+
+	return checkout.String() + rest, nil
+}
+
+// checkAmbiguousName returns an error if a plain name matches more than one
+// of a local branch, a tag, a remote branch or an abbreviated commit hash.
+func (repo *Repository) checkAmbiguousName(name string) error {
+	var candidates []string
+
+	for _, prefix := range []string{headRef, tagRef, remoteRef} {
+		ref, err := repo.Essence().References.Lookup(prefix + name)
+		if err != nil {
+			continue
+		}
+		Free(ref)
+
+		candidates = append(candidates, strings.TrimPrefix(prefix+name, "refs/"))
+	}
+
+	if len(candidates) > 0 && shortHashPattern.MatchString(name) {
+		if commit, err := repo.lookupPrefixCommit(name); err == nil {
+			candidates = append(candidates, commit.ID.String())
+			Free(commit)
+		}
+	}
+
+	if len(candidates) < 2 {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%w: %s could be %s, use one of them instead",
+		ErrAmbiguousRevision, name, strings.Join(candidates, ", "),
+	)
+}
+
+// errAmbiguousHash lists the commits an abbreviated hash matches.
+func (repo *Repository) errAmbiguousHash(spec string, hash string) error {
+	hash = strings.ToLower(hash)
+
+	odb, err := repo.Essence().Odb()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrAmbiguousRevision, spec)
+	}
+	defer Free(odb)
+
+	var candidates []string
+
+	_ = odb.ForEach(func(id *git.Oid) error {
+		if !strings.HasPrefix(id.String(), hash) {
+			return nil
+		}
+
+		gitCommit, err := repo.Essence().LookupCommit(id)
+		if err != nil {
+			return nil
+		}
+		defer Free(gitCommit)
+
+		candidates = append(candidates, fmt.Sprintf("  %s %s", id.String()[:12], gitCommit.Summary()))
+		return nil
+	})
+
+	if len(candidates) == 0 {
+		return fmt.Errorf("%w: %s matches more than one object, use a longer hash", ErrAmbiguousRevision, spec)
+	}
+
+	return fmt.Errorf(
+		"%w: %s matches more than one commit, use a longer hash\n%s",
+		ErrAmbiguousRevision, spec, strings.Join(candidates, "\n"),
+	)
+}
+
+func (repo *Repository) lookupPrefixCommit(hash string) (*Commit, error) {
+	id, err := git.NewOid(hash + strings.Repeat("0", 40-len(hash)))
+	if err != nil {
+		return nil, err
+	}
+
+	gitCommit, err := repo.Essence().LookupPrefixCommit(id, uint(len(hash)))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCommit(gitCommit), nil
+}
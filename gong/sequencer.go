@@ -0,0 +1,687 @@
+package gong
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+// sequenceAction is the action a sequence step applies to its commit.
+type sequenceAction string
+
+const (
+	sequencePick   sequenceAction = "pick"
+	sequenceRevert sequenceAction = "revert"
+)
+
+// sequenceStep is a commit replayed by a sequence e.g. a rebase, or reverted
+// by a revert.
+type sequenceStep struct {
+	Action sequenceAction
+	ID     *git.Oid
+}
+
+func (step sequenceStep) String() string {
+	return fmt.Sprintf("%s:%s", step.Action, step.ID.String())
+}
+
+// sequence is an operation replaying commits one by one on top of head, such
+// as a rebase, a pick or a revert. The state of a stopped sequence is recorded under the
+// name of the sequence with these values:
+//
+//	branch    the branch moved by the sequence, empty if head is detached
+//	original  the commit of head before the sequence
+//	stash     true if uncommitted changes were stashed for the sequence
+//	mainline  the parent number merge commits are replayed relative to
+//	origin    true if the replayed commits record the commit they came from
+//	todo      the steps left, the first being the stopped step
+type sequence struct {
+	Name  string
+	State map[string]string
+}
+
+// sequenceConflicts maps the sequences to the error returned when they stop.
+var sequenceConflicts = map[string]error{
+	rebaseState: ErrRebaseConflict,
+	pickState:   ErrPickConflict,
+	revertState: ErrRevertConflict,
+}
+
+// sequenceInProgress maps the sequences to the error returned when another
+// operation is started while they are stopped.
+var sequenceInProgress = map[string]error{
+	rebaseState: ErrRebaseInProgress,
+	pickState:   ErrPickInProgress,
+	revertState: ErrRevertInProgress,
+}
+
+func (seq *sequence) mainline() uint {
+	mainline, _ := strconv.ParseUint(seq.State["mainline"], 10, 32)
+	return uint(mainline)
+}
+
+func (seq *sequence) recordOrigin() bool {
+	return seq.State["origin"] == "true"
+}
+
+// formatSteps formats the steps as a state value.
+func formatSteps(steps []sequenceStep) string {
+	var values []string
+	for _, step := range steps {
+		values = append(values, step.String())
+	}
+
+	return strings.Join(values, " ")
+}
+
+// parseSteps parses the steps from a state value.
+func parseSteps(value string) ([]sequenceStep, error) {
+	var steps []sequenceStep
+
+	for _, field := range strings.Fields(value) {
+		parts := strings.SplitN(field, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid sequence step %s", field)
+		}
+
+		id, err := git.NewOid(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, sequenceStep{Action: sequenceAction(parts[0]), ID: id})
+	}
+
+	return steps, nil
+}
+
+// newSequence starts a sequence moving the current branch, or the detached
+// head.
+func (repo *Repository) newSequence(name string) (*sequence, error) {
+	if err := repo.checkSequenceAllowed(); err != nil {
+		return nil, err
+	}
+
+	if _, err := conflictStyle(); err != nil {
+		return nil, err
+	}
+
+	head, err := repo.Head.Commit()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(head)
+
+	state := map[string]string{
+		"original": head.ID.String(),
+	}
+
+	detached, err := repo.Head.IsDetached()
+	if err != nil {
+		return nil, err
+	}
+
+	if !detached {
+		branch, err := repo.CurrentBranch()
+		if err != nil {
+			return nil, err
+		}
+		defer Free(branch)
+
+		state["branch"] = branch.Name
+	}
+
+	return &sequence{Name: name, State: state}, nil
+}
+
+// stashForSequence stashes the uncommitted changes of the branch for the
+// sequence like when switching branches. The changes are popped when the
+// sequence ends.
+func (repo *Repository) stashForSequence(seq *sequence) error {
+	changed, err := repo.Changed()
+	if err != nil {
+		return err
+	}
+
+	seq.State["stash"] = strconv.FormatBool(changed)
+
+	if !changed {
+		return nil
+	}
+
+	if checkEmptyString(seq.State["branch"]) {
+		return errors.New("head is detached and has uncommitted changes, commit or stash the changes first")
+	}
+
+	branch, err := repo.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	defer Free(branch)
+
+	_, err = repo.Stashes.Create(branch)
+
+	return err
+}
+
+// readSequence reads the state of a stopped sequence and the steps left. The
+// error is returned if the sequence is not in progress.
+func (repo *Repository) readSequence(name string, notInProgress error) (*sequence, []sequenceStep, error) {
+	state, err := repo.readState(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if state == nil {
+		return nil, nil, notInProgress
+	}
+
+	steps, err := parseSteps(state["todo"])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(steps) == 0 {
+		return nil, nil, fmt.Errorf("%s state has no commits left, abort the %s", strings.ToLower(name), strings.ToLower(name))
+	}
+
+	return &sequence{Name: name, State: state}, steps, nil
+}
+
+// checkSequenceAllowed refuses to start an operation that moves head while
+// another operation is stopped.
+func (repo *Repository) checkSequenceAllowed() error {
+	switchInProgress, err := repo.SwitchInProgress()
+	if err != nil {
+		return err
+	}
+
+	if switchInProgress {
+		return ErrSwitchInProgress
+	}
+
+	if repo.MergeInProgress() {
+		return ErrMergeInProgress
+	}
+
+	for name, inProgressErr := range sequenceInProgress {
+		state, err := repo.readState(name)
+		if err != nil {
+			return err
+		}
+
+		if state != nil {
+			return inProgressErr
+		}
+	}
+
+	return nil
+}
+
+// runSequence replays the steps on top of the commit. If a step conflicts the
+// state of the sequence is recorded and the conflict is left to the working
+// tree, otherwise the sequence is finished.
+func (repo *Repository) runSequence(seq *sequence, ontoID *git.Oid, steps []sequenceStep) error {
+	headID, left, err := repo.replay(seq, ontoID, steps)
+	if err != nil {
+		return err
+	}
+
+	if len(left) == 0 {
+		return repo.finishSequence(seq, headID)
+	}
+
+	return repo.stopSequence(seq, headID, left)
+}
+
+// stopSequence records the state of the sequence with the steps left, the
+// first being the conflicting step, and leaves the conflict of the step on top
+// of the commit to the working tree.
+func (repo *Repository) stopSequence(seq *sequence, headID *git.Oid, left []sequenceStep) error {
+	seq.State["todo"] = formatSteps(left)
+
+	if err := repo.writeState(seq.Name, seq.State); err != nil {
+		return err
+	}
+
+	conflicts, err := repo.stopAtStep(seq, left[0], headID)
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w\n  %s: %s", sequenceConflicts[seq.Name], left[0].ID.String()[:7], strings.Join(conflicts, ", "))
+}
+
+// continueSequence commits the stopped step after the conflicts have been
+// resolved and replays the rest of the steps.
+func (repo *Repository) continueSequence(seq *sequence, steps []sequenceStep) error {
+	if err := repo.continueStep(seq, steps[0]); err != nil {
+		return err
+	}
+
+	return repo.resumeSequence(seq, steps[1:])
+}
+
+// resumeSequence replays the rest of the steps on top of head.
+func (repo *Repository) resumeSequence(seq *sequence, steps []sequenceStep) error {
+	head, err := repo.Head.Commit()
+	if err != nil {
+		return err
+	}
+	defer Free(head)
+
+	return repo.runSequence(seq, head.ID, steps)
+}
+
+// createdCommits returns the number of commits head is ahead of the commit
+// the sequence started from.
+func (repo *Repository) createdCommits(startID *git.Oid) (int, error) {
+	head, err := repo.Head.Commit()
+	if err != nil {
+		return 0, err
+	}
+	defer Free(head)
+
+	ahead, _, err := repo.Essence().AheadBehind(head.ID, startID)
+
+	return ahead, err
+}
+
+// finishSequence moves the branch, or the detached head, to the last replayed
+// commit and checks it out.
+func (repo *Repository) finishSequence(seq *sequence, headID *git.Oid) error {
+	head, err := repo.FindCommit(headID)
+	if err != nil {
+		return err
+	}
+	defer Free(head)
+
+	tree, err := head.Tree()
+	if err != nil {
+		return err
+	}
+	defer Free(tree)
+
+	checkoutOpts := &git.CheckoutOpts{
+		Strategy: git.CheckoutSafe | git.CheckoutRecreateMissing,
+	}
+
+	if err := repo.Essence().CheckoutTree(tree, checkoutOpts); err != nil {
+		return err
+	}
+
+	branchName := seq.State["branch"]
+
+	if checkEmptyString(branchName) {
+		if err := repo.Head.Detach(headID); err != nil {
+			return err
+		}
+
+		return repo.endSequence(seq)
+	}
+
+	branch, err := repo.FindBranch(branchName, git.BranchLocal)
+	if err != nil {
+		return err
+	}
+	defer Free(branch)
+
+	ref, err := branch.Essence().SetTarget(headID, fmt.Sprintf("%s finished: %s", strings.ToLower(seq.Name), branchName))
+	if err != nil {
+		return err
+	}
+	defer Free(ref)
+
+	if err := repo.Head.SetReference(branch.RefName); err != nil {
+		return err
+	}
+
+	return repo.endSequence(seq)
+}
+
+// abortSequence stops the sequence and returns to head as it was before the
+// sequence, restoring the stashed changes.
+func (repo *Repository) abortSequence(seq *sequence) error {
+	original, err := git.NewOid(seq.State["original"])
+	if err != nil {
+		return err
+	}
+
+	commit, err := repo.FindCommit(original)
+	if err != nil {
+		return err
+	}
+	defer Free(commit)
+
+	if branchName := seq.State["branch"]; !checkEmptyString(branchName) {
+		if err := repo.Head.SetReference(headRef + branchName); err != nil {
+			return err
+		}
+	}
+
+	checkoutOpts := &git.CheckoutOpts{
+		Strategy: git.CheckoutForce,
+	}
+
+	if err := repo.Essence().ResetToCommit(commit.Essence(), git.ResetHard, checkoutOpts); err != nil {
+		return err
+	}
+
+	if err := repo.Essence().StateCleanup(); err != nil {
+		return err
+	}
+
+	return repo.endSequence(seq)
+}
+
+// endSequence removes the state of the sequence and pops the changes stashed
+// for the sequence.
+func (repo *Repository) endSequence(seq *sequence) error {
+	if err := repo.removeState(seq.Name); err != nil {
+		return err
+	}
+
+	if seq.State["stash"] != "true" {
+		return nil
+	}
+
+	branch, err := repo.FindBranch(seq.State["branch"], git.BranchLocal)
+	if err != nil {
+		return err
+	}
+	defer Free(branch)
+
+	if !repo.Stashes.Has(branch) {
+		return nil
+	}
+
+	if err := repo.Stashes.Pop(branch); err != nil {
+		if errors.Is(err, ErrStashConflict) {
+			return fmt.Errorf("%w, the stash of branch %s was kept", err, branch.Name)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// applyStep applies the step on top of the commit in memory and returns the
+// resulting index, which has conflicts if the step does not apply cleanly.
+func (repo *Repository) applyStep(seq *sequence, step sequenceStep, onto *Commit) (*git.Index, error) {
+	commit, err := repo.FindCommit(step.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(commit)
+
+	mainline, err := stepMainline(seq, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if step.Action == sequenceRevert {
+		mergeOpts, err := git.DefaultMergeOptions()
+		if err != nil {
+			return nil, err
+		}
+
+		return repo.Essence().RevertCommit(commit.Essence(), onto.Essence(), mainline, &mergeOpts)
+	}
+
+	opts, err := git.DefaultCherrypickOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Mainline = mainline
+
+	return repo.Essence().CherrypickCommit(commit.Essence(), onto.Essence(), opts)
+}
+
+// stepMainline returns the mainline parent number the commit is replayed
+// relative to. Only merge commits have a mainline.
+func stepMainline(seq *sequence, commit *Commit) (uint, error) {
+	parents := commit.Essence().ParentCount()
+
+	if parents <= 1 {
+		return 0, nil
+	}
+
+	mainline := seq.mainline()
+
+	if mainline == 0 {
+		return 0, fmt.Errorf("commit %s is a merge commit, select the mainline parent", commit.ID.String()[:7])
+	}
+
+	if mainline > parents {
+		return 0, fmt.Errorf("commit %s does not have the parent %d", commit.ID.String()[:7], mainline)
+	}
+
+	return mainline, nil
+}
+
+// stepMessage returns the message of the commit created by the step.
+func stepMessage(seq *sequence, step sequenceStep, commit *Commit) string {
+	if step.Action == sequenceRevert {
+		return revertMessage(seq, commit)
+	}
+
+	message := commit.Essence().Message()
+
+	if !seq.recordOrigin() {
+		return message
+	}
+
+	return fmt.Sprintf("%s\n\n(cherry picked from commit %s)\n", strings.TrimRight(message, "\n"), commit.ID.String())
+}
+
+// replay applies the steps one by one on top of the commit in memory without
+// touching the working tree. It returns the last replayed commit and the steps
+// that are left when a step conflicts, the first of them being the
+// conflicting step. Steps whose changes are already applied are skipped.
+func (repo *Repository) replay(seq *sequence, ontoID *git.Oid, steps []sequenceStep) (*git.Oid, []sequenceStep, error) {
+	for i, step := range steps {
+		onto, err := repo.FindCommit(ontoID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		index, err := repo.applyStep(seq, step, onto)
+		if err != nil {
+			Free(onto)
+			return nil, nil, err
+		}
+
+		if index.HasConflicts() {
+			Free(index)
+			Free(onto)
+			return ontoID, steps[i:], nil
+		}
+
+		treeID, err := index.WriteTreeTo(repo.Essence())
+		Free(index)
+		if err != nil {
+			Free(onto)
+			return nil, nil, err
+		}
+
+		commitID, err := repo.commitStep(seq, step, treeID, onto, "")
+		Free(onto)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if commitID != nil {
+			ontoID = commitID
+		}
+	}
+
+	return ontoID, nil, nil
+}
+
+// commitStep commits the tree on top of the commit with the author and the
+// message of the step commit, and updates the reference if it is not empty.
+// Nil is returned if the tree is unchanged as the step was already applied.
+func (repo *Repository) commitStep(seq *sequence, step sequenceStep, treeID *git.Oid, onto *Commit, refName string) (*git.Oid, error) {
+	if treeID.Equal(onto.Essence().TreeId()) {
+		return nil, nil
+	}
+
+	commit, err := repo.FindCommit(step.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(commit)
+
+	tree, err := repo.FindTree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(tree)
+
+	// A revert is new work of the committer rather than of the original author.
+	author := commit.Essence().Author()
+	if step.Action == sequenceRevert {
+		author = signature()
+	}
+
+	return repo.Essence().CreateCommit(
+		refName,
+		author,
+		signature(),
+		stepMessage(seq, step, commit),
+		tree,
+		onto.Essence(),
+	)
+}
+
+// stopAtStep detaches head to the commit and applies the conflicting step to
+// the working tree, leaving the conflict markers in place. The conflicted
+// paths are returned.
+func (repo *Repository) stopAtStep(seq *sequence, step sequenceStep, headID *git.Oid) ([]string, error) {
+	style, err := conflictStyle()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repo.FindCommit(headID)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(head)
+
+	tree, err := head.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(tree)
+
+	checkoutOpts := &git.CheckoutOpts{
+		Strategy: git.CheckoutSafe | git.CheckoutRecreateMissing,
+	}
+
+	if err := repo.Essence().CheckoutTree(tree, checkoutOpts); err != nil {
+		return nil, err
+	}
+
+	if err := repo.Head.Detach(headID); err != nil {
+		return nil, err
+	}
+
+	commit, err := repo.FindCommit(step.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(commit)
+
+	mainline, err := stepMainline(seq, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := git.CheckoutSafe | git.CheckoutAllowConflicts | style
+
+	if step.Action == sequenceRevert {
+		opts, err := git.DefaultRevertOptions()
+		if err != nil {
+			return nil, err
+		}
+
+		opts.Mainline = mainline
+		opts.CheckoutOpts.Strategy = strategy
+
+		if err := repo.Essence().Revert(commit.Essence(), &opts); err != nil {
+			return nil, err
+		}
+
+		return repo.Conflicts()
+	}
+
+	opts, err := git.DefaultCherrypickOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Mainline = mainline
+	opts.CheckoutOpts.Strategy = strategy
+
+	if err := repo.Essence().Cherrypick(commit.Essence(), opts); err != nil {
+		return nil, err
+	}
+
+	return repo.Conflicts()
+}
+
+// continueStep commits the step stopped by conflicts on top of head after the
+// conflicts have been resolved in the working tree.
+func (repo *Repository) continueStep(seq *sequence, step sequenceStep) error {
+	if err := repo.resolveConflicts(); err != nil {
+		return err
+	}
+
+	index, err := repo.Essence().Index()
+	if err != nil {
+		return err
+	}
+	defer Free(index)
+
+	treeID, err := index.WriteTree()
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head.Commit()
+	if err != nil {
+		return err
+	}
+	defer Free(head)
+
+	if _, err := repo.commitStep(seq, step, treeID, head, repo.Head.RefName); err != nil {
+		return err
+	}
+
+	return repo.Essence().StateCleanup()
+}
+
+// skipStep drops the changes of the step stopped by conflicts.
+func (repo *Repository) skipStep() error {
+	head, err := repo.Head.Commit()
+	if err != nil {
+		return err
+	}
+	defer Free(head)
+
+	checkoutOpts := &git.CheckoutOpts{
+		Strategy: git.CheckoutForce,
+	}
+
+	if err := repo.Essence().ResetToCommit(head.Essence(), git.ResetHard, checkoutOpts); err != nil {
+		return err
+	}
+
+	return repo.Essence().StateCleanup()
+}
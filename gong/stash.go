@@ -1,9 +1,24 @@
 package gong
 
-import git "github.com/libgit2/git2go/v31"
+import (
+	"time"
+
+	git "github.com/libgit2/git2go/v31"
+)
 
 type Stash struct {
 	ID      *git.Oid
 	Message string
+	// Name is the branch name for auto-stashes and the given name for named stashes.
+	Name string
+	// Branch is the branch the auto-stash is bound to, empty for named stashes.
+	Branch  string
+	RefName string
 	Index   int
+	Created time.Time
+}
+
+// IsAuto reports whether the stash is an auto-stash bound to a branch.
+func (stash *Stash) IsAuto() bool {
+	return stash.Branch != ""
 }
@@ -2,56 +2,282 @@ package gong
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	git "github.com/libgit2/git2go/v31"
 )
 
+const (
+	// stashRef is the reference namespace binding auto-stashes to branches.
+	// Each auto-stash is referenced by refs/gong/stash/<branchname>, which keeps
+	// the stash bound to the branch regardless of new commits or stash reindexing.
+	stashRef = "refs/gong/stash/"
+	// namedStashRef is the reference namespace of manually named stashes.
+	namedStashRef = "refs/gong/named-stash/"
+	// carryStashRef references the changes carried to another branch for the
+	// duration of the switch.
+	carryStashRef = "refs/gong/carry/"
+)
+
 type StashCollection struct {
-	essence *git.StashCollection
-	stashes map[string]*Stash
+	essence    *git.StashCollection
+	repository *git.Repository
+	stashes    map[string]*Stash
+	named      map[string]*Stash
+	// dangling are the stashes whose binding reference remains although the
+	// stash has been dropped from the stash list outside gong.
+	dangling []*Stash
 }
 
-func NewStashCollection(gitStash *git.StashCollection) *StashCollection {
-	stashes := make(map[string]*Stash)
+func NewStashCollection(gitRepo *git.Repository) *StashCollection {
+	collection := &StashCollection{
+		essence:    &gitRepo.Stashes,
+		repository: gitRepo,
+		stashes:    make(map[string]*Stash),
+		named:      make(map[string]*Stash),
+	}
 
-	gitStash.Foreach(func(index int, message string, id *git.Oid) error {
-		stashes[message] = &Stash{ID: id, Message: message, Index: index}
+	iter, err := gitRepo.NewReferenceNameIterator()
+	if err != nil {
+		return collection
+	}
+	defer Free(iter)
+
+	name, err := iter.Next()
+	for err == nil {
+		switch {
+		case strings.HasPrefix(name, stashRef):
+			branchName := strings.TrimPrefix(name, stashRef)
+			collection.bind(collection.stashes, name, branchName, branchName)
+		case strings.HasPrefix(name, namedStashRef):
+			collection.bind(collection.named, name, strings.TrimPrefix(name, namedStashRef), "")
+		}
+		name, err = iter.Next()
+	}
+
+	collection.findDangling()
+
+	return collection
+}
+
+// findDangling moves the stashes that no longer exist in the stash list out
+// of the bound stashes, so that a dangling binding does not count as a stash.
+func (collection *StashCollection) findDangling() {
+	ids := make(map[string]bool)
+
+	err := collection.Essence().Foreach(func(_ int, _ string, id *git.Oid) error {
+		ids[id.String()] = true
 		return nil
 	})
+	if err != nil {
+		return
+	}
+
+	for _, all := range []map[string]*Stash{collection.stashes, collection.named} {
+		for name, stash := range all {
+			if !ids[stash.ID.String()] {
+				collection.dangling = append(collection.dangling, stash)
+				delete(all, name)
+			}
+		}
+	}
+
+	sort.Slice(collection.dangling, func(i, j int) bool {
+		return collection.dangling[i].RefName < collection.dangling[j].RefName
+	})
+}
 
-	return &StashCollection{
-		essence: gitStash,
-		stashes: stashes,
+// Dangling returns the stashes whose binding remains although the stash has
+// been dropped from the stash list outside gong.
+func (collection *StashCollection) Dangling() []*Stash {
+	return collection.dangling
+}
+
+// PruneDangling removes the bindings of the dangling stashes.
+func (collection *StashCollection) PruneDangling() error {
+	for len(collection.dangling) > 0 {
+		if err := collection.removeDangling(collection.dangling[0].RefName); err != nil {
+			return err
+		}
 	}
+
+	return nil
+}
+
+// removeDangling removes the dangling binding by the reference name, if any,
+// so that the name can be bound again.
+func (collection *StashCollection) removeDangling(refName string) error {
+	for i, stash := range collection.dangling {
+		if stash.RefName != refName {
+			continue
+		}
+
+		collection.dangling = append(collection.dangling[:i], collection.dangling[i+1:]...)
+
+		ref, err := collection.repository.References.Lookup(refName)
+		if err != nil {
+			if git.IsErrorCode(err, git.ErrorCodeNotFound) {
+				return nil
+			}
+			return err
+		}
+		defer Free(ref)
+
+		return ref.Delete()
+	}
+
+	return nil
+}
+
+// bound reports whether the reference binding a stash exists.
+func (collection *StashCollection) bound(refName string) bool {
+	ref, err := collection.repository.References.Lookup(refName)
+	if err != nil {
+		return false
+	}
+	Free(ref)
+
+	return true
+}
+
+func (collection *StashCollection) bind(stashes map[string]*Stash, refName string, name string, branchName string) {
+	ref, err := collection.repository.References.Lookup(refName)
+	if err != nil {
+		return
+	}
+	defer Free(ref)
+
+	stashes[name] = &Stash{ID: ref.Target(), Name: name, Branch: branchName, RefName: refName, Index: -1}
 }
 
 func (collection *StashCollection) Essence() *git.StashCollection {
 	return collection.essence
 }
 
+// Create stashes the uncommitted changes including untracked files and binds
+// the stash to the given branch.
 func (collection *StashCollection) Create(currentBranch *Branch) (*Stash, error) {
-	branchID := currentBranch.ReferenceID.String()
-	stashID, err := collection.Essence().Save(signature(), branchID, git.StashIncludeUntracked)
+	if collection.Has(currentBranch) {
+		return nil, fmt.Errorf("branch %s already has stashed changes, pop or drop the stash first", currentBranch.Name)
+	}
+
+	stash, err := collection.save(stashRef, currentBranch.Name, fmt.Sprintf("gong: %s", currentBranch.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	stash.Branch = currentBranch.Name
+	collection.stashes[currentBranch.Name] = stash
+
+	return stash, nil
+}
+
+// Save stashes the uncommitted changes including untracked files as a
+// manually named stash. Named stashes are not popped when switching branches.
+func (collection *StashCollection) Save(name string) (*Stash, error) {
+	if _, ok := collection.named[name]; ok {
+		return nil, fmt.Errorf("stash with name %s already exists", name)
+	}
+
+	stash, err := collection.save(namedStashRef, name, fmt.Sprintf("gong: %s", name))
 	if err != nil {
 		return nil, err
 	}
 
-	stash := &Stash{ID: stashID, Message: branchID, Index: 0}
-	collection.stashes[branchID] = stash
+	collection.named[name] = stash
 
 	return stash, nil
 }
 
+// Carry stashes the uncommitted changes including untracked files to be
+// carried to the branch. The stash is neither bound to a branch nor named.
+func (collection *StashCollection) Carry(branchName string) (*Stash, error) {
+	return collection.save(carryStashRef, branchName, fmt.Sprintf("gong: carry to %s", branchName))
+}
+
+func (collection *StashCollection) save(namespace string, name string, message string) (*Stash, error) {
+	if err := collection.removeDangling(namespace + name); err != nil {
+		return nil, err
+	}
+
+	// Check before stashing, the changes would otherwise be left in a stash
+	// nothing is bound to.
+	if collection.bound(namespace + name) {
+		return nil, fmt.Errorf("%s already has stashed changes, pop or drop the stash first", name)
+	}
+
+	stashID, err := collection.Essence().Save(signature(), message, git.StashIncludeUntracked)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := collection.repository.References.Create(namespace+name, stashID, false, message)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(ref)
+
+	return &Stash{ID: stashID, Message: message, Name: name, RefName: namespace + name, Index: 0}, nil
+}
+
+// Find returns the stash bound to the branch.
 func (collection *StashCollection) Find(branch *Branch) (*Stash, error) {
-	if stash, ok := collection.stashes[branch.ReferenceID.String()]; ok {
-		return stash, nil
+	stash, ok := collection.stashes[branch.Name]
+	if !ok {
+		return nil, fmt.Errorf("stash with name %s was not found", branch.Name)
 	}
 
-	return nil, fmt.Errorf("stash with name %s was not found", branch.Name)
+	return stash, collection.resolve(stash)
 }
 
+// Lookup returns the stash by name. The name is either the name of the
+// branch the auto-stash is bound to or the name of a named stash.
+func (collection *StashCollection) Lookup(name string) (*Stash, error) {
+	if stash, ok := collection.stashes[name]; ok {
+		return stash, collection.resolve(stash)
+	}
+
+	if stash, ok := collection.named[name]; ok {
+		return stash, collection.resolve(stash)
+	}
+
+	for _, stash := range collection.dangling {
+		if stash.Name == name {
+			return stash, fmt.Errorf("stash %s was dropped outside gong, drop it to remove the binding", name)
+		}
+	}
+
+	return nil, fmt.Errorf("stash with name %s was not found", name)
+}
+
+// resolve resolves the stash index from the stash list by the stash id, as
+// the index changes whenever stashes are created or dropped.
+func (collection *StashCollection) resolve(stash *Stash) error {
+	stash.Index = -1
+
+	err := collection.Essence().Foreach(func(i int, message string, id *git.Oid) error {
+		if id.Equal(stash.ID) {
+			stash.Index = i
+			stash.Message = message
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if stash.Index < 0 {
+		return fmt.Errorf("stash %s no longer exists in the stash list", stash.Name)
+	}
+
+	return nil
+}
+
+// Has reports whether a stash is bound to the branch. A binding whose stash
+// has been dropped outside gong does not count.
 func (collection *StashCollection) Has(branch *Branch) bool {
-	_, ok := collection.stashes[branch.ReferenceID.String()]
+	_, ok := collection.stashes[branch.Name]
 	return ok
 }
 
@@ -61,20 +287,262 @@ func (collection *StashCollection) Pop(branch *Branch) error {
 		return err
 	}
 
+	return collection.PopStash(stash)
+}
+
+// Apply applies the stash to the working tree and keeps the stash.
+// Conflicting changes are written to the working tree with conflict markers
+// and ErrStashConflict is returned.
+func (collection *StashCollection) Apply(stash *Stash) error {
+	if err := collection.resolve(stash); err != nil {
+		return err
+	}
+
 	opts, err := git.DefaultStashApplyOptions()
 	if err != nil {
 		return err
 	}
 
-	if err := collection.Essence().Pop(stash.Index, opts); err != nil {
+	opts.CheckoutOptions.Strategy = git.CheckoutSafe | git.CheckoutAllowConflicts
+
+	if err := collection.Essence().Apply(stash.Index, opts); err != nil {
+		return err
+	}
+
+	index, err := collection.repository.Index()
+	if err != nil {
+		return err
+	}
+	defer Free(index)
+
+	if index.HasConflicts() {
+		return ErrStashConflict
+	}
+
+	return nil
+}
+
+// PopStash applies the stash to the working tree and drops the stash.
+// If the stash conflicts the stash is kept.
+func (collection *StashCollection) PopStash(stash *Stash) error {
+	if err := collection.Apply(stash); err != nil {
+		return err
+	}
+
+	return collection.Drop(stash)
+}
+
+// Drop removes the stash without applying it. Dropping a dangling stash
+// removes its binding.
+func (collection *StashCollection) Drop(stash *Stash) error {
+	for _, dangling := range collection.dangling {
+		if dangling.RefName == stash.RefName {
+			return collection.removeDangling(stash.RefName)
+		}
+	}
+
+	if err := collection.resolve(stash); err != nil {
+		return err
+	}
+
+	if err := collection.Essence().Drop(stash.Index); err != nil {
+		return err
+	}
+
+	return collection.unbind(stash)
+}
+
+// Rename binds the stash of branch oldName to branch newName.
+func (collection *StashCollection) Rename(oldName string, newName string) error {
+	stash, ok := collection.stashes[oldName]
+	if !ok {
+		return nil
+	}
+
+	ref, err := collection.repository.References.Lookup(stash.RefName)
+	if err != nil {
+		return err
+	}
+	defer Free(ref)
+
+	renamed, err := ref.Rename(stashRef+newName, false, fmt.Sprintf("gong: rename %s to %s", oldName, newName))
+	if err != nil {
+		return err
+	}
+	defer Free(renamed)
+
+	stash.Name = newName
+	stash.Branch = newName
+	stash.RefName = stashRef + newName
+	collection.stashes[newName] = stash
+	delete(collection.stashes, oldName)
+
+	return nil
+}
+
+// keepCarried binds the carried stash to the branch as its auto-stash, so
+// that changes that could not be carried can be popped or dropped with the
+// stash commands.
+func (collection *StashCollection) keepCarried(stash *Stash, branchName string) error {
+	ref, err := collection.repository.References.Lookup(stash.RefName)
+	if err != nil {
+		return err
+	}
+	defer Free(ref)
+
+	kept, err := ref.Rename(stashRef+branchName, false, fmt.Sprintf("gong: keep carried changes on %s", branchName))
+	if err != nil {
+		return err
+	}
+	defer Free(kept)
+
+	stash.Name = branchName
+	stash.Branch = branchName
+	stash.RefName = stashRef + branchName
+	collection.stashes[branchName] = stash
+
+	return nil
+}
+
+// Bind binds the stash by the stash id to the branch.
+func (collection *StashCollection) Bind(branchName string, stashID *git.Oid) error {
+	refName := stashRef + branchName
+
+	if err := collection.removeDangling(refName); err != nil {
+		return err
+	}
+
+	ref, err := collection.repository.References.Create(refName, stashID, false, fmt.Sprintf("gong: bind stash to %s", branchName))
+	if err != nil {
 		return err
 	}
+	defer Free(ref)
 
-	delete(collection.stashes, branch.ReferenceID.String())
+	collection.stashes[branchName] = &Stash{ID: stashID, Name: branchName, Branch: branchName, RefName: refName, Index: -1}
 
 	return nil
 }
 
+// unbind removes the reference binding the stash.
+func (collection *StashCollection) unbind(stash *Stash) error {
+	// Carried stashes are in neither of the collections.
+	switch {
+	case strings.HasPrefix(stash.RefName, stashRef):
+		delete(collection.stashes, stash.Name)
+	case strings.HasPrefix(stash.RefName, namedStashRef):
+		delete(collection.named, stash.Name)
+	}
+
+	ref, err := collection.repository.References.Lookup(stash.RefName)
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrorCodeNotFound) {
+			return nil
+		}
+		return err
+	}
+	defer Free(ref)
+
+	return ref.Delete()
+}
+
 func (collection *StashCollection) Stashes() map[string]*Stash {
 	return collection.stashes
 }
+
+// List returns the auto-stashes and named stashes ordered by the stash index.
+// Dangling stashes are left out and returned by Dangling.
+func (collection *StashCollection) List() ([]*Stash, error) {
+	var stashes []*Stash
+
+	for _, all := range []map[string]*Stash{collection.stashes, collection.named} {
+		for name, stash := range all {
+			if err := collection.resolve(stash); err != nil {
+				// The stash was dropped outside gong after the collection was read.
+				collection.dangling = append(collection.dangling, stash)
+				delete(all, name)
+				continue
+			}
+
+			commit, err := collection.repository.LookupCommit(stash.ID)
+			if err != nil {
+				return nil, err
+			}
+			stash.Created = commit.Committer().When
+			Free(commit)
+
+			stashes = append(stashes, stash)
+		}
+	}
+
+	sort.Slice(stashes, func(i, j int) bool {
+		return stashes[i].Index < stashes[j].Index
+	})
+
+	return stashes, nil
+}
+
+// Diff returns the changes recorded in the stash as a patch, including the
+// stashed untracked files.
+func (collection *StashCollection) Diff(stash *Stash) (string, error) {
+	commit, err := collection.repository.LookupCommit(stash.ID)
+	if err != nil {
+		return "", err
+	}
+	defer Free(commit)
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", err
+	}
+	defer Free(tree)
+
+	base := commit.Parent(0)
+	defer Free(base)
+
+	baseTree, err := base.Tree()
+	if err != nil {
+		return "", err
+	}
+	defer Free(baseTree)
+
+	patch, err := collection.patch(baseTree, tree)
+	if err != nil {
+		return "", err
+	}
+
+	// Untracked files are stored to the third parent of the stash commit.
+	if commit.ParentCount() < 3 {
+		return patch, nil
+	}
+
+	untrackedCommit := commit.Parent(2)
+	defer Free(untrackedCommit)
+
+	untrackedTree, err := untrackedCommit.Tree()
+	if err != nil {
+		return "", err
+	}
+	defer Free(untrackedTree)
+
+	untracked, err := collection.patch(nil, untrackedTree)
+	if err != nil {
+		return "", err
+	}
+
+	return patch + untracked, nil
+}
+
+func (collection *StashCollection) patch(oldTree *git.Tree, newTree *git.Tree) (string, error) {
+	diff, err := collection.repository.DiffTreeToTree(oldTree, newTree, nil)
+	if err != nil {
+		return "", err
+	}
+	defer diff.Free()
+
+	patch, err := diff.ToBuf(git.DiffFormatPatch)
+	if err != nil {
+		return "", err
+	}
+
+	return string(patch), nil
+}
@@ -0,0 +1,81 @@
+package gong
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/erikjuhani/git-gong/fs"
+)
+
+// stateDir is the directory inside the git directory where gong records the
+// state of operations that can be continued or aborted.
+const stateDir = "gong"
+
+func (repo *Repository) statePath(name string) string {
+	return filepath.Join(repo.GitPath, stateDir, name)
+}
+
+// writeState records the state of an operation as key value lines.
+func (repo *Repository) writeState(name string, values map[string]string) error {
+	if err := fs.EnsureDir(filepath.Join(repo.GitPath, stateDir)); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(repo.statePath(name), []byte(formatState(values)), 0644)
+}
+
+// formatState formats the values as key value lines sorted by the key.
+func formatState(values map[string]string) string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sb := strings.Builder{}
+	for _, key := range keys {
+		sb.WriteString(fmt.Sprintf("%s %s\n", key, values[key]))
+	}
+
+	return sb.String()
+}
+
+// parseState parses key value lines formatted by formatState.
+func parseState(content string) map[string]string {
+	values := make(map[string]string)
+
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) == 2 {
+			values[fields[0]] = fields[1]
+		}
+	}
+
+	return values
+}
+
+// readState reads the state of an operation. If the operation is not in
+// progress nil is returned.
+func (repo *Repository) readState(name string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(repo.statePath(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseState(string(content)), nil
+}
+
+func (repo *Repository) removeState(name string) error {
+	if err := os.Remove(repo.statePath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+package gong
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+// switchState records a switch that stopped because the stash of the target
+// branch conflicted.
+const switchState = "SWITCH"
+
+// SwitchInProgress reports whether a stopped switch waits to be continued or aborted.
+func (repo *Repository) SwitchInProgress() (bool, error) {
+	state, err := repo.readState(switchState)
+	return state != nil, err
+}
+
+// popSwitchStash pops the stash of the branch switched to. If the stash does
+// not apply cleanly the stash is kept, conflict markers are left to the working
+// tree and the switch is recorded so it can be continued or aborted.
+func (repo *Repository) popSwitchStash(from string, branch *Branch) error {
+	stash, err := repo.Stashes.Find(branch)
+	if err != nil {
+		return err
+	}
+
+	applyErr := repo.Stashes.Apply(stash)
+	if applyErr == nil {
+		return repo.Stashes.Drop(stash)
+	}
+
+	state := map[string]string{
+		"from":  from,
+		"to":    branch.Name,
+		"stash": stash.ID.String(),
+	}
+
+	if err := repo.writeState(switchState, state); err != nil {
+		return err
+	}
+
+	if errors.Is(applyErr, ErrStashConflict) {
+		return ErrSwitchConflict
+	}
+
+	return fmt.Errorf("could not apply the stash of branch %s: %w. Run gong switch --abort to return to %s", branch.Name, applyErr, from)
+}
+
+// ContinueSwitch finishes a stopped switch after the conflicts have been
+// resolved. The resolved files are left unstaged like the rest of the changes
+// of the stash, and the applied stash is dropped.
+func (repo *Repository) ContinueSwitch() (*Branch, error) {
+	state, err := repo.readState(switchState)
+	if err != nil {
+		return nil, err
+	}
+
+	if state == nil {
+		return nil, ErrNoSwitchInProgress
+	}
+
+	paths, err := repo.Conflicts()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.resolveConflicts(); err != nil {
+		return nil, err
+	}
+
+	if err := repo.unstage(paths); err != nil {
+		return nil, err
+	}
+
+	// Only the auto-stash of the branch was applied, a named stash may have
+	// the same name as the branch.
+	if stash, ok := repo.Stashes.Stashes()[state["to"]]; ok {
+		if err := repo.Stashes.Drop(stash); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := repo.removeState(switchState); err != nil {
+		return nil, err
+	}
+
+	return repo.CurrentBranch()
+}
+
+// unstage resets the index entries of the paths to head, keeping the changes
+// in the working tree.
+func (repo *Repository) unstage(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	headCommit, err := repo.Head.Commit()
+	if err != nil {
+		return err
+	}
+	defer Free(headCommit)
+
+	return repo.Essence().ResetDefaultToCommit(headCommit.Essence(), paths)
+}
+
+// AbortSwitch restores the state before a stopped switch. The half applied
+// stash is removed from the working tree and kept in the stash list, and the
+// previous branch is checked out with its own stash.
+func (repo *Repository) AbortSwitch() (*Branch, error) {
+	state, err := repo.readState(switchState)
+	if err != nil {
+		return nil, err
+	}
+
+	if state == nil {
+		return nil, ErrNoSwitchInProgress
+	}
+
+	headCommit, err := repo.Head.Commit()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(headCommit)
+
+	checkoutOpts := &git.CheckoutOpts{
+		Strategy: git.CheckoutForce,
+	}
+
+	if err := repo.Essence().ResetToCommit(headCommit.Essence(), git.ResetHard, checkoutOpts); err != nil {
+		return nil, err
+	}
+
+	stashID, err := git.NewOid(state["stash"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.removeStashedUntracked(stashID); err != nil {
+		return nil, err
+	}
+
+	if err := repo.removeState(switchState); err != nil {
+		return nil, err
+	}
+
+	return repo.CheckoutBranch(state["from"])
+}
+
+// removeStashedUntracked removes the untracked files the stash wrote to the
+// working tree. Untracked files are stored to the third parent of the stash.
+func (repo *Repository) removeStashedUntracked(stashID *git.Oid) error {
+	stashCommit, err := repo.FindCommit(stashID)
+	if err != nil {
+		return err
+	}
+	defer Free(stashCommit)
+
+	if stashCommit.Essence().ParentCount() < 3 {
+		return nil
+	}
+
+	untracked := stashCommit.Essence().Parent(2)
+	defer Free(untracked)
+
+	tree, err := untracked.Tree()
+	if err != nil {
+		return err
+	}
+	defer Free(tree)
+
+	var removeErr error
+
+	err = tree.Walk(func(root string, entry *git.TreeEntry) int {
+		if entry.Type != git.ObjectBlob {
+			return 0
+		}
+
+		if err := os.Remove(filepath.Join(repo.Path, root, entry.Name)); err != nil && !os.IsNotExist(err) {
+			removeErr = err
+			return -1
+		}
+
+		return 0
+	})
+	if removeErr != nil {
+		return removeErr
+	}
+
+	return err
+}
+
+// Switch resolves the target and switches to it. The target is resolved in
+// order as a local branch, a remote branch and finally with ResolveRevision as
+// a tag, a release, a commit hash or any other revision. If create is true a
+// new branch is created from head instead.
+func (repo *Repository) Switch(target string, create bool) (*Revision, error) {
+	if create {
+		branch, err := repo.CreateLocalBranch(target)
+		if err != nil {
+			return nil, err
+		}
+		Free(branch)
+
+		return repo.switchBranch(target, &Revision{Spec: target, Kind: RevisionBranch, Name: target})
+	}
+
+	if branch, err := repo.FindBranch(target, git.BranchLocal); err == nil {
+		Free(branch)
+
+		return repo.switchBranch(target, &Revision{Spec: target, Kind: RevisionBranch, Name: target})
+	}
+
+	remoteBranches, err := repo.RemoteBranches(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(remoteBranches) > 0 {
+		return repo.switchBranch(target, &Revision{Spec: target, Kind: RevisionRemoteBranch, Name: remoteBranches[0], RefName: remoteRef + remoteBranches[0]})
+	}
+
+	revision, err := repo.ResolveRevision(target)
+	if err != nil {
+		if errors.Is(err, ErrRevisionNotFound) {
+			return nil, fmt.Errorf("no branch, remote branch, release, tag or commit found by %s, use --create to create a new branch", target)
+		}
+		return nil, err
+	}
+
+	if revision.Kind == RevisionBranch {
+		Free(revision)
+
+		return repo.switchBranch(revision.Name, &Revision{Spec: target, Kind: RevisionBranch, Name: revision.Name})
+	}
+
+	if err := repo.checkoutRevision(revision); err != nil {
+		Free(revision)
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+func (repo *Repository) switchBranch(branchName string, revision *Revision) (*Revision, error) {
+	branch, err := repo.CheckoutBranch(branchName)
+	if err != nil {
+		return nil, err
+	}
+	defer Free(branch)
+
+	commit, err := repo.FindCommit(branch.ReferenceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revision.Kind == RevisionBranch {
+		revision.RefName = branch.RefName
+	}
+	revision.Commit = commit
+
+	return revision, nil
+}
@@ -0,0 +1,100 @@
+package gong
+
+import (
+	"fmt"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+// Track sets the upstream of the current branch to the remote branch
+// upstreamName e.g. origin/main.
+func (repo *Repository) Track(upstreamName string) (*Branch, error) {
+	currentBranch, err := repo.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(currentBranch)
+
+	remoteBranch, err := repo.FindBranch(upstreamName, git.BranchRemote)
+	if err != nil {
+		return nil, fmt.Errorf("no remote branch found by branch name %s", upstreamName)
+	}
+	defer Free(remoteBranch)
+
+	if err := currentBranch.Essence().SetUpstream(remoteBranch.Shorthand); err != nil {
+		return nil, err
+	}
+
+	return repo.FindBranch(currentBranch.Name, git.BranchLocal)
+}
+
+// Untrack removes the upstream of the current branch.
+func (repo *Repository) Untrack() (*Branch, error) {
+	currentBranch, err := repo.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(currentBranch)
+
+	if !currentBranch.HasUpstream() {
+		return nil, fmt.Errorf("branch %s does not track an upstream branch", currentBranch.Name)
+	}
+
+	cfg, err := repo.Essence().Config()
+	if err != nil {
+		return nil, err
+	}
+	defer Free(cfg)
+
+	for _, key := range []string{"remote", "merge"} {
+		if err := cfg.Delete(fmt.Sprintf("branch.%s.%s", currentBranch.Name, key)); err != nil {
+			return nil, err
+		}
+	}
+
+	return repo.FindBranch(currentBranch.Name, git.BranchLocal)
+}
+
+// DefaultRemote returns the name of the remote used when a branch has no
+// upstream. If the repository has a single remote it is used, otherwise origin.
+func (repo *Repository) DefaultRemote() (string, error) {
+	remotes, err := repo.Essence().Remotes.List()
+	if err != nil {
+		return "", err
+	}
+
+	if len(remotes) == 0 {
+		return "", fmt.Errorf("repository has no remotes")
+	}
+
+	if len(remotes) == 1 {
+		return remotes[0], nil
+	}
+
+	return "origin", nil
+}
+
+// RemoteBranches returns the remote branches, in the form <remote>/<name>,
+// that have the given branch name on any of the remotes.
+func (repo *Repository) RemoteBranches(branchName string) ([]string, error) {
+	remotes, err := repo.Essence().Remotes.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var remoteBranches []string
+
+	for _, remote := range remotes {
+		remoteBranchName := fmt.Sprintf("%s/%s", remote, branchName)
+
+		branch, err := repo.FindBranch(remoteBranchName, git.BranchRemote)
+		if err != nil {
+			continue
+		}
+		Free(branch)
+
+		remoteBranches = append(remoteBranches, remoteBranchName)
+	}
+
+	return remoteBranches, nil
+}
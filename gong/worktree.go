@@ -0,0 +1,267 @@
+package gong
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+// worktreesDir is the directory inside the common git directory where git
+// keeps the administrative files of the linked worktrees.
+const worktreesDir = "worktrees"
+
+// Worktree is a working tree of the repository. The main worktree has an
+// empty Name. Branch is empty when head of the worktree is detached, in
+// which case Commit holds the detached commit hash.
+type Worktree struct {
+	Name    string
+	Path    string
+	Branch  string
+	Commit  string
+	Locked  bool
+	Current bool
+}
+
+// IsMain reports whether the worktree is the main worktree.
+func (worktree *Worktree) IsMain() bool {
+	return worktree.Name == ""
+}
+
+// commonDir returns the git directory shared by all worktrees. For the main
+// worktree it is the git directory itself.
+func (repo *Repository) commonDir() string {
+	content, err := ioutil.ReadFile(filepath.Join(repo.GitPath, "commondir"))
+	if err != nil {
+		return filepath.Clean(repo.GitPath)
+	}
+
+	dir := strings.TrimSpace(string(content))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repo.GitPath, dir)
+	}
+
+	return filepath.Clean(dir)
+}
+
+// IsLinkedWorktree reports whether the repository was opened from a linked
+// worktree rather than the main worktree.
+func (repo *Repository) IsLinkedWorktree() bool {
+	return repo.commonDir() != filepath.Clean(repo.GitPath)
+}
+
+// Worktrees returns the main worktree followed by the linked worktrees.
+func (repo *Repository) Worktrees() ([]*Worktree, error) {
+	commonDir := repo.commonDir()
+	current := filepath.Clean(repo.Path)
+
+	main := &Worktree{Path: filepath.Dir(commonDir)}
+	if err := readWorktreeHead(main, filepath.Join(commonDir, "HEAD")); err != nil {
+		return nil, err
+	}
+
+	worktrees := []*Worktree{main}
+
+	entries, err := ioutil.ReadDir(filepath.Join(commonDir, worktreesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		adminDir := filepath.Join(commonDir, worktreesDir, entry.Name())
+
+		gitdir, err := ioutil.ReadFile(filepath.Join(adminDir, "gitdir"))
+		if err != nil {
+			continue
+		}
+
+		worktree := &Worktree{
+			Name: entry.Name(),
+			Path: filepath.Dir(strings.TrimSpace(string(gitdir))),
+		}
+
+		if err := readWorktreeHead(worktree, filepath.Join(adminDir, "HEAD")); err != nil {
+			return nil, err
+		}
+
+		if _, err := os.Stat(filepath.Join(adminDir, "locked")); err == nil {
+			worktree.Locked = true
+		}
+
+		worktrees = append(worktrees, worktree)
+	}
+
+	for _, worktree := range worktrees {
+		worktree.Current = filepath.Clean(worktree.Path) == current
+	}
+
+	return worktrees, nil
+}
+
+func readWorktreeHead(worktree *Worktree, headPath string) error {
+	content, err := ioutil.ReadFile(headPath)
+	if err != nil {
+		return err
+	}
+
+	head := strings.TrimSpace(string(content))
+
+	if strings.HasPrefix(head, "ref: ") {
+		worktree.Branch = strings.TrimPrefix(strings.TrimPrefix(head, "ref: "), headRef)
+		return nil
+	}
+
+	worktree.Commit = head
+
+	return nil
+}
+
+// worktreeOf returns the other worktree that has the branch checked out, or
+// nil if no other worktree has.
+func (repo *Repository) worktreeOf(branchName string) (*Worktree, error) {
+	worktrees, err := repo.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, worktree := range worktrees {
+		if !worktree.Current && worktree.Branch == branchName {
+			return worktree, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// FindWorktree finds a worktree by its branch name, name or path.
+func (repo *Repository) FindWorktree(target string) (*Worktree, error) {
+	worktrees, err := repo.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	absTarget, _ := filepath.Abs(target)
+
+	for _, worktree := range worktrees {
+		if worktree.Branch == target || (!worktree.IsMain() && worktree.Name == target) || filepath.Clean(worktree.Path) == absTarget {
+			return worktree, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no worktree found by %s", target)
+}
+
+// SwitchWorktree creates a linked worktree for the branch at the path, or
+// reuses the worktree that already has the branch checked out. An empty
+// path places the worktree next to the main worktree. The branch is created
+// like in CheckoutBranch when it does not exist. The second return value is
+// true when a new worktree was created.
+func (repo *Repository) SwitchWorktree(branchName string, path string) (*Worktree, bool, error) {
+	worktree, err := repo.worktreeOf(branchName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if worktree != nil {
+		return worktree, false, nil
+	}
+
+	currentBranch, err := repo.CurrentBranch()
+	if err == nil {
+		defer Free(currentBranch)
+
+		if currentBranch.Name == branchName {
+			return nil, false, fmt.Errorf("branch %s is checked out in the current worktree", branchName)
+		}
+	}
+
+	branch, err := repo.FindBranch(branchName, git.BranchLocal)
+	if err != nil {
+		branch, err = repo.createCheckoutBranch(branchName)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	defer Free(branch)
+
+	if checkEmptyString(path) {
+		path = repo.defaultWorktreePath(branchName)
+	}
+
+	if _, err := repo.runGit("worktree", "add", path, branchName); err != nil {
+		return nil, false, err
+	}
+
+	worktree, err = repo.FindWorktree(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return worktree, true, nil
+}
+
+// defaultWorktreePath returns the path of a worktree for the branch next to
+// the main worktree, e.g. ../gong-feature-login for the branch feature/login.
+func (repo *Repository) defaultWorktreePath(branchName string) string {
+	main := filepath.Dir(repo.commonDir())
+
+	return filepath.Join(
+		filepath.Dir(main),
+		fmt.Sprintf("%s-%s", filepath.Base(main), strings.ReplaceAll(branchName, "/", "-")),
+	)
+}
+
+// RemoveWorktree removes a linked worktree found by its branch name, name or
+// path. Worktrees with changes are only removed when force is true.
+func (repo *Repository) RemoveWorktree(target string, force bool) (*Worktree, error) {
+	worktree, err := repo.FindWorktree(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if worktree.IsMain() {
+		return nil, errors.New("the main worktree cannot be removed")
+	}
+
+	if worktree.Current {
+		return nil, errors.New("the current worktree cannot be removed, run the command from another worktree")
+	}
+
+	args := []string{"worktree", "remove"}
+	if force {
+		args = append(args, "--force")
+	}
+
+	if _, err := repo.runGit(append(args, worktree.Path)...); err != nil {
+		return nil, err
+	}
+
+	return worktree, nil
+}
+
+// runGit runs a git command in the worktree of the repository for the
+// operations libgit2 does not support, and returns its output.
+func (repo *Repository) runGit(args ...string) (string, error) {
+	if !commandExists("git") {
+		return "", errors.New("git executable not found in path")
+	}
+
+	var stderr bytes.Buffer
+
+	command := exec.Command("git", args...)
+	command.Dir = repo.Path
+	command.Stderr = &stderr
+
+	output, err := command.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+
+	return string(output), nil
+}